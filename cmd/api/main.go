@@ -7,6 +7,10 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/fdemchenko/arcus/internal/app"
 	"github.com/fdemchenko/arcus/internal/config"
@@ -37,23 +41,96 @@ func main() {
 	channel, err := conn.Channel()
 	handleCriticalError(err, "failed to create RabbitMQ channel", logger)
 
-	usersRepo := &postgres.UsersRepository{DB: db}
-	tokensRepo := &postgres.TokensRepository{DB: db}
-	postsRepo := &postgres.PostsRepository{DB: db}
-
-	mailerService := mail.NewMailSender(cfg.SMTPMailer, templates.TemplatesFS)
-	consumer, err := mail.NewMailerConsumer(mailerService, channel, logger)
+	usersRepo := &postgres.UsersRepository{DB: db, QueryTimeout: cfg.Storage.QueryTimeout}
+	tokensRepo := &postgres.TokensRepository{DB: db, QueryTimeout: cfg.Storage.QueryTimeout}
+	postsRepo := &postgres.PostsRepository{DB: db, QueryTimeout: cfg.Storage.QueryTimeout}
+	tagsRepo := &postgres.TagsRepository{DB: db, QueryTimeout: cfg.Storage.QueryTimeout}
+
+	usersUnitOfWork := postgres.NewUnitOfWork(db, func(q postgres.DBTX) services.UsersRepositories {
+		return services.UsersRepositories{
+			Users:  &postgres.UsersRepository{DB: q, QueryTimeout: cfg.Storage.QueryTimeout},
+			Tokens: &postgres.TokensRepository{DB: q, QueryTimeout: cfg.Storage.QueryTimeout},
+			Outbox: &postgres.OutboxRepository{DB: q, QueryTimeout: cfg.Storage.QueryTimeout},
+		}
+	})
+	postsUnitOfWork := postgres.NewUnitOfWork(db, func(q postgres.DBTX) services.PostsRepositories {
+		return services.PostsRepositories{
+			Posts: &postgres.PostsRepository{DB: q, QueryTimeout: cfg.Storage.QueryTimeout},
+			Tags:  &postgres.TagsRepository{DB: q, QueryTimeout: cfg.Storage.QueryTimeout},
+		}
+	})
+	outboxUnitOfWork := postgres.NewUnitOfWork(db, func(q postgres.DBTX) services.OutboxRepository {
+		return &postgres.OutboxRepository{DB: q, QueryTimeout: cfg.Storage.QueryTimeout}
+	})
+
+	transport, mailbox, err := newMailTransport(cfg)
+	handleCriticalError(err, "failed to create mail transport", logger)
+
+	mailerService, err := mail.NewMailSender(cfg.SMTPMailer.SenderAddress, transport, templates.TemplatesFS)
+	handleCriticalError(err, "failed to load email templates", logger)
+	templateRegistry := mail.DefaultTemplateRegistry()
+	consumerOptions := mail.ConsumerOptions{
+		Workers:   cfg.MailConsumer.Workers,
+		Prefetch:  cfg.MailConsumer.Prefetch,
+		RateLimit: cfg.MailConsumer.RateLimit,
+		RateBurst: cfg.MailConsumer.RateBurst,
+		Retry: mail.RetryPolicy{
+			MaxAttempts: cfg.MailConsumer.MaxAttempts,
+			BaseDelay:   cfg.MailConsumer.RetryBaseDelay,
+			MaxDelay:    cfg.MailConsumer.RetryMaxDelay,
+		},
+	}
+	consumer, err := mail.NewMailerConsumer(mailerService, channel, logger, templateRegistry, consumerOptions)
 	handleCriticalError(err, "failer to create mailer consumer", logger)
 
 	err = consumer.StartConsuming()
 	handleCriticalError(err, "failer to start mailer consumer", logger)
 
-	producer, err := mail.NewMailerProducer(channel)
+	producer, err := mail.NewMailerProducer(cfg.RabbitMQConnString)
 	handleCriticalError(err, "failer to create mailer producer", logger)
 
-	userService := services.NewUserService(usersRepo, logger, tokensRepo, producer, cfg.ActivationTokenTTL)
-	postsService := services.NewPostsService(logger, postsRepo)
-	application := app.New(userService, postsService, logger)
+	// background workers are stopped only after HTTP server is shut down
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	if cfg.Env == EnvDevelopment && cfg.MailTemplates.HotReload {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			templatesDir := os.DirFS(cfg.MailTemplates.Directory)
+			mailerService.WatchTemplates(workersCtx, templatesDir, cfg.MailTemplates.ReloadInterval, logger)
+		}()
+	}
+
+	outboxRelay := services.NewOutboxRelay(logger, outboxUnitOfWork, producer, cfg.Outbox.BatchSize, cfg.Outbox.MaxAttempts)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		outboxRelay.Run(workersCtx, cfg.Outbox.PollInterval)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		outboxRelay.RunPurge(workersCtx, cfg.Outbox.PurgeInterval, cfg.Outbox.Retention)
+	}()
+
+	userService := services.NewUserService(usersRepo, logger, tokensRepo, usersUnitOfWork,
+		cfg.ActivationTokenTTL, cfg.AuthenticationTokenTTL, cfg.PasswordResetTokenTTL,
+		strings.TrimSuffix(cfg.Frontend.BaseURL, "/")+cfg.Frontend.ActivationPath)
+	postsService := services.NewPostsService(logger, postsRepo, postsUnitOfWork, cfg.CursorSigningKey)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		postsService.RunTrashPurge(workersCtx, cfg.Trash.PurgeInterval, cfg.Trash.Retention)
+	}()
+	tagsService := services.NewTagsService(logger, tagsRepo, postsUnitOfWork)
+	emailTemplatesService := services.NewEmailTemplatesService(logger, mailerService, templateRegistry, producer)
+	appConfig := app.Config{
+		RequireIfMatch:       cfg.HTTPServer.RequireIfMatch,
+		ActivationSuccessURL: cfg.Frontend.ActivationSuccessURL,
+		ActivationFailureURL: cfg.Frontend.ActivationFailureURL,
+	}
+	application := app.New(appConfig, userService, postsService, tagsService, emailTemplatesService, mailbox, logger)
 
 	// http server start
 	address := fmt.Sprintf("%s:%d", cfg.HTTPServer.Host, cfg.HTTPServer.Port)
@@ -62,11 +139,55 @@ func main() {
 		Handler: application.Routes(),
 	}
 
-	logger.Info("starting application web server", slog.String("address", address))
-	err = server.ListenAndServe()
-	if err != nil {
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		logger.Info("starting application web server", slog.String("address", address))
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErrors:
 		logger.Error("http server error", slog.String("error", err.Error()))
+	case <-signalCtx.Done():
+		logger.Info("shutdown signal received")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	// drain in-flight requests first, as they may still use other components
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown http server gracefully", slog.String("error", err.Error()))
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	if err := producer.Close(); err != nil {
+		logger.Error("failed to close mailer producer", slog.String("error", err.Error()))
+	}
+
+	if err := consumer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown mailer consumer gracefully", slog.String("error", err.Error()))
+	}
+
+	if err := mailerService.Close(); err != nil {
+		logger.Error("failed to close mail transport", slog.String("error", err.Error()))
+	}
+
+	if err := channel.Close(); err != nil {
+		logger.Error("failed to close RabbitMQ channel", slog.String("error", err.Error()))
 	}
+	if err := conn.Close(); err != nil {
+		logger.Error("failed to close RabbitMQ connection", slog.String("error", err.Error()))
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("failed to close DB connections pool", slog.String("error", err.Error()))
+	}
+	logger.Info("application stopped")
 }
 
 func openDB(dbConfig config.StorageConfig) (*sql.DB, error) {
@@ -89,6 +210,25 @@ func openDB(dbConfig config.StorageConfig) (*sql.DB, error) {
 	return db, nil
 }
 
+// newMailTransport also returns captured emails store, when it should be exposed in development.
+func newMailTransport(cfg *config.Config) (mail.Transport, app.Mailbox, error) {
+	switch cfg.MailTransport.Kind {
+	case "smtp":
+		return mail.NewSMTPTransport(cfg.SMTPMailer), nil, nil
+	case "file":
+		transport, err := mail.NewFileTransport(cfg.MailTransport.Directory)
+		return transport, nil, err
+	case "memory":
+		transport := mail.NewMemoryTransport(cfg.MailTransport.MemoryCapacity)
+		if cfg.Env != EnvDevelopment {
+			return transport, nil, nil
+		}
+		return transport, transport, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown mail transport: %s", cfg.MailTransport.Kind)
+	}
+}
+
 func initLogger(env string) *slog.Logger {
 	switch env {
 	case EnvDevelopment:
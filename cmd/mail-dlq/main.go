@@ -0,0 +1,85 @@
+// Command mail-dlq inspects and replays emails from the dead letter queue.
+//
+// Usage:
+//
+//	mail-dlq -config-path=config.yaml [-limit=N] inspect|replay
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fdemchenko/arcus/internal/config"
+	"github.com/fdemchenko/arcus/internal/services/mail"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var errUsage = errors.New("usage: mail-dlq -config-path=<path> [-limit=N] inspect|replay")
+
+func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	limit := flag.Int("limit", 100, "Maximum number of dead letters to process")
+
+	var cfg config.RabbitMQConfig
+	err := config.Read(&cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	command := flag.Arg(0)
+	if command != "inspect" && command != "replay" {
+		return errUsage
+	}
+
+	conn, err := amqp.Dial(cfg.RabbitMQConnString)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to create RabbitMQ channel: %w", err)
+	}
+	defer channel.Close()
+
+	dlq, err := mail.NewDeadLetterQueue(channel)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead letter queue: %w", err)
+	}
+
+	switch command {
+	case "inspect":
+		deadLetters, err := dlq.Peek(*limit)
+		if err != nil {
+			return fmt.Errorf("failed to inspect dead letter queue: %w", err)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(deadLetters); err != nil {
+			return fmt.Errorf("failed to print dead letters: %w", err)
+		}
+	case "replay":
+		replayed, err := dlq.Replay(context.Background(), *limit)
+		fmt.Printf("replayed %d dead letters\n", replayed)
+		if err != nil {
+			return fmt.Errorf("failed to replay dead letter queue: %w", err)
+		}
+	}
+	return nil
+}
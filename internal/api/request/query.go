@@ -0,0 +1,53 @@
+package request
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fdemchenko/arcus/internal/validator"
+)
+
+func ReadString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+func ReadCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+	if csv == "" {
+		return defaultValue
+	}
+	return strings.Split(csv, ",")
+}
+
+func ReadInt(qs url.Values, key string, defaultValue int, v validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.Check(false, key, "must be an integer value")
+		return defaultValue
+	}
+	return i
+}
+
+func ReadBool(qs url.Values, key string, defaultValue bool, v validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.Check(false, key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
@@ -15,3 +15,34 @@ func SendServerError(w http.ResponseWriter) {
 	message := "the server encountered a problem and could not process your request"
 	SendError(w, http.StatusInternalServerError, message)
 }
+
+func SendInvalidAuthenticationTokenError(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	message := "invalid or missing authentication token"
+	SendError(w, http.StatusUnauthorized, message)
+}
+
+func SendAuthenticationRequiredError(w http.ResponseWriter) {
+	message := "you must be authenticated to access this resource"
+	SendError(w, http.StatusUnauthorized, message)
+}
+
+func SendNotPermittedError(w http.ResponseWriter) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	SendError(w, http.StatusForbidden, message)
+}
+
+func SendInactiveAccountError(w http.ResponseWriter) {
+	message := "your user account must be activated to access this resource"
+	SendError(w, http.StatusForbidden, message)
+}
+
+func SendPreconditionFailedError(w http.ResponseWriter) {
+	message := "the resource has been modified since you last retrieved it, please try again"
+	SendError(w, http.StatusPreconditionFailed, message)
+}
+
+func SendPreconditionRequiredError(w http.ResponseWriter) {
+	message := "this request is required to be conditional, provide If-Match header"
+	SendError(w, http.StatusPreconditionRequired, message)
+}
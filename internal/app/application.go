@@ -1,36 +1,92 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
 	"log/slog"
 
 	"github.com/fdemchenko/arcus/internal/models"
+	"github.com/fdemchenko/arcus/internal/services/mail"
 )
 
 type Application struct {
-	userService  UserService
-	postsService PostsService
-	logger       *slog.Logger
+	config                Config
+	userService           UserService
+	postsService          PostsService
+	tagsService           TagsService
+	emailTemplatesService EmailTemplatesService
+	logger                *slog.Logger
+	// mailbox is set only in development, when emails are captured in memory
+	mailbox Mailbox
+}
+
+type Config struct {
+	// RequireIfMatch makes If-Match header mandatory for post modifications
+	RequireIfMatch bool
+	// users are redirected to these URLs after following activation link
+	ActivationSuccessURL string
+	ActivationFailureURL string
 }
 
 type UserService interface {
-	Register(models.User) (int, error)
-	Activate(token string) error
-	SendActivationToken(user models.User) error
-	GetByID(userID int) (*models.User, error)
+	Register(ctx context.Context, user models.User) (int, error)
+	Activate(ctx context.Context, token string) error
+	SendActivationToken(ctx context.Context, user models.User) error
+	GetByID(ctx context.Context, userID int) (*models.User, error)
+	Authenticate(ctx context.Context, email, password string) (*models.Token, error)
+	GetForToken(ctx context.Context, tokenPlaintext string, scope string) (*models.User, error)
+	SendPasswordResetToken(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, tokenPlaintext string, newPassword string) error
 }
 
 type PostsService interface {
-	Create(models.Post) (int, error)
-	GetAll() ([]models.Post, error)
-	GetByID(int) (*models.Post, error)
-	DeleteByID(int) (int, error)
-	UpdateByID(models.Post) error
+	Create(ctx context.Context, post models.Post) (int, error)
+	GetAll(ctx context.Context, filter models.PostsFilter) ([]models.Post, models.Metadata, error)
+	GetAllByCursor(ctx context.Context, filter models.PostsFilter, cursor string) ([]models.Post, string, error)
+	GetByID(ctx context.Context, id int) (*models.Post, error)
+	DeleteByID(ctx context.Context, id int, version int) (int, error)
+	UpdateByID(ctx context.Context, post models.Post) error
+	GetDeletedByID(ctx context.Context, id int) (*models.Post, error)
+	GetTrash(ctx context.Context, userID int) ([]models.Post, error)
+	Restore(ctx context.Context, id int) error
+	GetRevisions(ctx context.Context, postID int) ([]models.PostRevision, error)
+	GetRevision(ctx context.Context, post models.Post, version int) (*models.PostRevision, error)
+	DiffRevisions(ctx context.Context, post models.Post, fromVersion, toVersion int) (string, error)
+	RestoreRevision(ctx context.Context, post models.Post, version int) (*models.Post, error)
+}
+
+type TagsService interface {
+	GetAll(ctx context.Context) ([]models.Tag, error)
+	Merge(ctx context.Context, sources []string, target string) (int, error)
+}
+
+type EmailTemplatesService interface {
+	GetAll() []mail.TemplateInfo
+	Preview(templateName string, locale string, data json.RawMessage) (*mail.Email, error)
+	TestSend(ctx context.Context, templateName string, to string, locale string, data json.RawMessage) error
+}
+
+type Mailbox interface {
+	Emails(to string) []mail.Email
+	Clear()
 }
 
-func New(userService UserService, postsService PostsService, logger *slog.Logger) *Application {
+func New(
+	config Config,
+	userService UserService,
+	postsService PostsService,
+	tagsService TagsService,
+	emailTemplatesService EmailTemplatesService,
+	mailbox Mailbox,
+	logger *slog.Logger,
+) *Application {
 	return &Application{
-		userService:  userService,
-		postsService: postsService,
-		logger:       logger,
+		config:                config,
+		userService:           userService,
+		postsService:          postsService,
+		tagsService:           tagsService,
+		emailTemplatesService: emailTemplatesService,
+		mailbox:               mailbox,
+		logger:                logger,
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/fdemchenko/arcus/internal/api/request"
@@ -12,6 +13,7 @@ import (
 	"github.com/fdemchenko/arcus/internal/models"
 	"github.com/fdemchenko/arcus/internal/repositories"
 	"github.com/fdemchenko/arcus/internal/repositories/postgres"
+	"github.com/fdemchenko/arcus/internal/services"
 	"github.com/fdemchenko/arcus/internal/validator"
 )
 
@@ -22,6 +24,7 @@ func (app *Application) registerUser(w http.ResponseWriter, r *http.Request) {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Locale   string `json:"locale"`
 	}
 
 	err := request.ReadJSON(r.Body, &input)
@@ -32,8 +35,12 @@ func (app *Application) registerUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	newUser := models.User{
-		Name:  strings.TrimSpace(input.Name),
-		Email: strings.TrimSpace(input.Email)}
+		Name:   strings.TrimSpace(input.Name),
+		Email:  strings.TrimSpace(input.Email),
+		Locale: strings.ToLower(strings.TrimSpace(input.Locale))}
+	if newUser.Locale == "" {
+		newUser.Locale = models.DefaultLocale
+	}
 	newUser.Password.Plain = strings.TrimSpace(input.Password)
 
 	v := validator.New()
@@ -44,7 +51,7 @@ func (app *Application) registerUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := app.userService.Register(newUser)
+	id, err := app.userService.Register(r.Context(), newUser)
 	if err != nil {
 		logger.Error("failed to register user", slog.String("error", err.Error()))
 		if errors.Is(err, repositories.ErrEmailAlreadyExists) {
@@ -84,7 +91,7 @@ func (app *Application) activateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := app.userService.Activate(input.Token); err != nil {
+	if err := app.userService.Activate(r.Context(), input.Token); err != nil {
 		if errors.Is(err, postgres.ErrTokenNotFound) {
 			response.SendError(w, http.StatusNotFound, err.Error())
 			return
@@ -98,6 +105,43 @@ func (app *Application) activateUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// activateUserByLink handles activation link from the welcome email and redirects to the frontend.
+func (app *Application) activateUserByLink(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.activateUserByLink"
+	logger := app.logger.With(slog.String("op", op))
+
+	token := request.ReadString(r.URL.Query(), "token", "")
+	if !models.IsValidTokenPlaintext(token) {
+		app.redirectActivationFailure(w, r, "invalid_token")
+		return
+	}
+
+	if err := app.userService.Activate(r.Context(), token); err != nil {
+		if errors.Is(err, postgres.ErrTokenNotFound) {
+			app.redirectActivationFailure(w, r, "invalid_token")
+			return
+		}
+		logger.Error("failed to activate user", slog.String("error", err.Error()))
+		app.redirectActivationFailure(w, r, "server_error")
+		return
+	}
+
+	http.Redirect(w, r, app.config.ActivationSuccessURL, http.StatusSeeOther)
+}
+
+func (app *Application) redirectActivationFailure(w http.ResponseWriter, r *http.Request, reason string) {
+	failureURL, err := url.Parse(app.config.ActivationFailureURL)
+	if err != nil {
+		response.SendServerError(w)
+		return
+	}
+
+	query := failureURL.Query()
+	query.Set("reason", reason)
+	failureURL.RawQuery = query.Encode()
+	http.Redirect(w, r, failureURL.String(), http.StatusSeeOther)
+}
+
 func (app *Application) resendActivationToken(w http.ResponseWriter, r *http.Request) {
 	const op = "app.routes.resendActivatonToken"
 	logger := app.logger.With(slog.String("op", op))
@@ -112,7 +156,7 @@ func (app *Application) resendActivationToken(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	user, err := app.userService.GetByID(input.UserID)
+	user, err := app.userService.GetByID(r.Context(), input.UserID)
 	if err != nil {
 		logger.Error("failed to get user by id", slog.String("err", err.Error()))
 		if errors.Is(err, postgres.ErrUserDoesNotExists) {
@@ -123,7 +167,7 @@ func (app *Application) resendActivationToken(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = app.userService.SendActivationToken(*user)
+	err = app.userService.SendActivationToken(r.Context(), *user)
 	if err != nil {
 		logger.Error("failed to send activation token", slog.String("err", err.Error()))
 		response.SendServerError(w)
@@ -131,3 +175,134 @@ func (app *Application) resendActivationToken(w http.ResponseWriter, r *http.Req
 	}
 	w.WriteHeader(http.StatusCreated)
 }
+
+func (app *Application) loginUser(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.loginUser"
+	logger := app.logger.With(slog.String("op", op))
+
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	err := request.ReadJSON(r.Body, &input)
+	if err != nil {
+		logger.Error("failed to decode JSON user input", slog.String("error", err.Error()))
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input.Email = strings.TrimSpace(input.Email)
+	input.Password = strings.TrimSpace(input.Password)
+
+	v := validator.New()
+	v.Check(input.Email != "", "email", "should not be empty")
+	v.Check(validator.IsValidEmail(input.Email), "email", "should be valid email")
+	v.Check(input.Password != "", "password", "should not be empty")
+	if !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	token, err := app.userService.Authenticate(r.Context(), input.Email, input.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			response.SendError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		logger.Error("failed to authenticate user", slog.String("error", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	envelope := response.Envelope{
+		"authentication_token": response.Envelope{
+			"token":      token.PlainText,
+			"expires_at": token.ExpiresAt,
+		},
+	}
+	if err := response.WriteJSON(w, http.StatusCreated, envelope); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) requestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.requestPasswordReset"
+	logger := app.logger.With(slog.String("op", op))
+
+	var input struct {
+		Email string `json:"email"`
+	}
+	err := request.ReadJSON(r.Body, &input)
+	if err != nil {
+		logger.Error("failed to decode JSON user input", slog.String("error", err.Error()))
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input.Email = strings.TrimSpace(input.Email)
+
+	v := validator.New()
+	v.Check(input.Email != "", "email", "should not be empty")
+	v.Check(validator.IsValidEmail(input.Email), "email", "should be valid email")
+	if !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	err = app.userService.SendPasswordResetToken(r.Context(), input.Email)
+	// do not reveal whether account with such email exists
+	if err != nil && !errors.Is(err, postgres.ErrUserDoesNotExists) {
+		logger.Error("failed to send password reset token", slog.String("error", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	envelope := response.Envelope{"message": "an email will be sent to you containing password reset instructions"}
+	if err := response.WriteJSON(w, http.StatusAccepted, envelope); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) resetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.resetPassword"
+	logger := app.logger.With(slog.String("op", op))
+
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	err := request.ReadJSON(r.Body, &input)
+	if err != nil {
+		logger.Error("failed to decode JSON user input", slog.String("error", err.Error()))
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input.Password = strings.TrimSpace(input.Password)
+
+	v := validator.New()
+	v.Check(input.Token != "", "token", "must not be empty")
+	v.Check(len(input.Token) == models.TokenBytesLength*2, "token", fmt.Sprintf("must be %d characters long", models.TokenBytesLength*2))
+	v.Check(input.Password != "", "password", "should not be empty")
+	v.Check(models.CheckUserPassword(input.Password), "password", fmt.Sprintf("password should be at least %d characters long", models.MinPasswordLength))
+	if !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	err = app.userService.ResetPassword(r.Context(), input.Token, input.Password)
+	if err != nil {
+		if errors.Is(err, postgres.ErrTokenNotFound) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		logger.Error("failed to reset password", slog.String("error", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	envelope := response.Envelope{"message": "your password was successfully reset"}
+	if err := response.WriteJSON(w, http.StatusOK, envelope); err != nil {
+		response.SendServerError(w)
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/fdemchenko/arcus/internal/api/request"
+	"github.com/fdemchenko/arcus/internal/api/response"
+	"github.com/fdemchenko/arcus/internal/services/mail"
+	"github.com/fdemchenko/arcus/internal/validator"
+)
+
+func (app *Application) getEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	templates := app.emailTemplatesService.GetAll()
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"templates": templates}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) previewEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.previewEmailTemplate"
+	logger := app.logger.With(slog.String("op", op))
+
+	var input struct {
+		Locale string          `json:"locale"`
+		Data   json.RawMessage `json:"data"`
+	}
+	err := request.ReadJSON(r.Body, &input)
+	if err != nil {
+		logger.Error("failed to decode JSON user input", slog.String("error", err.Error()))
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	email, err := app.emailTemplatesService.Preview(r.PathValue("name"), strings.TrimSpace(input.Locale), input.Data)
+	if err != nil {
+		logger.Error("failed to render email template", slog.String("error", err.Error()))
+		sendEmailTemplateError(w, err)
+		return
+	}
+
+	envelope := response.Envelope{
+		"subject":    email.Subject,
+		"plain_body": email.PlainBody,
+		"html_body":  email.HTMLBody,
+	}
+	if err := response.WriteJSON(w, http.StatusOK, envelope); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) testSendEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.testSendEmailTemplate"
+	logger := app.logger.With(slog.String("op", op))
+
+	var input struct {
+		To     string          `json:"to"`
+		Locale string          `json:"locale"`
+		Data   json.RawMessage `json:"data"`
+	}
+	err := request.ReadJSON(r.Body, &input)
+	if err != nil {
+		logger.Error("failed to decode JSON user input", slog.String("error", err.Error()))
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	to := strings.TrimSpace(input.To)
+	v := validator.New()
+	v.Check(validator.IsValidEmail(to), "to", "should be valid email")
+	if !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	err = app.emailTemplatesService.TestSend(r.Context(), r.PathValue("name"), to, strings.TrimSpace(input.Locale), input.Data)
+	if err != nil {
+		logger.Error("failed to queue test email", slog.String("error", err.Error()))
+		sendEmailTemplateError(w, err)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusAccepted, response.Envelope{"message": "test email has been queued"}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func sendEmailTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, mail.ErrUnknownTemplate):
+		response.SendError(w, http.StatusNotFound, "email template not found")
+	case errors.Is(err, mail.ErrInvalidTemplateData):
+		response.SendError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		response.SendServerError(w)
+	}
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fdemchenko/arcus/internal/api/response"
+	"github.com/fdemchenko/arcus/internal/models"
+)
+
+// postETag derives entity tag from post version, which is bumped on every modification.
+func postETag(post models.Post) string {
+	return fmt.Sprintf(`"%d"`, post.Version)
+}
+
+// checkIfMatch evaluates If-Match precondition against the current post state.
+// If precondition fails, error response is sent and false is returned.
+func (app *Application) checkIfMatch(w http.ResponseWriter, r *http.Request, post models.Post) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if app.config.RequireIfMatch {
+			response.SendPreconditionRequiredError(w)
+			return false
+		}
+		return true
+	}
+
+	// If-Match uses strong comparison, so weak tags never match
+	if !etagListContains(header, postETag(post), false) {
+		response.SendPreconditionFailedError(w)
+		return false
+	}
+	return true
+}
+
+// isNotModified reports whether If-None-Match header matches the current post state.
+func isNotModified(r *http.Request, post models.Post) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	return etagListContains(header, postETag(post), true)
+}
+
+func etagListContains(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
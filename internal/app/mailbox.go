@@ -0,0 +1,22 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/fdemchenko/arcus/internal/api/request"
+	"github.com/fdemchenko/arcus/internal/api/response"
+)
+
+func (app *Application) getMailbox(w http.ResponseWriter, r *http.Request) {
+	to := request.ReadString(r.URL.Query(), "to", "")
+	emails := app.mailbox.Emails(to)
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"emails": emails}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) clearMailbox(w http.ResponseWriter, r *http.Request) {
+	app.mailbox.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,10 +1,16 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/fdemchenko/arcus/internal/api/response"
+	"github.com/fdemchenko/arcus/internal/models"
+	"github.com/fdemchenko/arcus/internal/repositories/postgres"
 )
 
 func (app *Application) RecoveryMiddleware(next http.Handler) http.Handler {
@@ -37,3 +43,78 @@ func (app *Application) LoggingMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (app *Application) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "app.middleware.Authenticate"
+		logger := app.logger.With(slog.String("op", op))
+
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+		if authorizationHeader == "" {
+			next.ServeHTTP(w, app.contextSetUser(r, models.AnonymousUser))
+			return
+		}
+
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			response.SendInvalidAuthenticationTokenError(w)
+			return
+		}
+
+		token := headerParts[1]
+		if len(token) != models.TokenBytesLength*2 {
+			response.SendInvalidAuthenticationTokenError(w)
+			return
+		}
+
+		user, err := app.userService.GetForToken(r.Context(), token, models.ScopeAuthentication)
+		if err != nil {
+			if errors.Is(err, postgres.ErrTokenNotFound) {
+				response.SendInvalidAuthenticationTokenError(w)
+				return
+			}
+			logger.Error("failed to get user for token", slog.String("error", err.Error()))
+			response.SendServerError(w)
+			return
+		}
+
+		next.ServeHTTP(w, app.contextSetUser(r, user))
+	})
+}
+
+func (app *Application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if user.IsAnonymous() {
+			response.SendAuthenticationRequiredError(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func (app *Application) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !user.Activated {
+			response.SendInactiveAccountError(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return app.requireAuthenticatedUser(fn)
+}
+
+func (app *Application) requireAdminUser(next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !user.IsAdmin {
+			response.SendNotPermittedError(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return app.requireActivatedUser(fn)
+}
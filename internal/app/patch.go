@@ -0,0 +1,53 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fdemchenko/arcus/internal/api/request"
+	"github.com/fdemchenko/arcus/internal/jsonpatch"
+	"github.com/fdemchenko/arcus/internal/models"
+)
+
+var errReadOnlyPostField = errors.New("patch must not modify id, version, user_id, created_at or updated_at")
+
+// applyPostPatch applies merge patch or JSON patch (depending on content type)
+// to JSON representation of the post and decodes the result back.
+func applyPostPatch(post models.Post, contentType string, body io.Reader) (*models.Post, error) {
+	patch, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := json.Marshal(post)
+	if err != nil {
+		return nil, err
+	}
+
+	var patchedDocument []byte
+	switch contentType {
+	case jsonpatch.MergePatchContentType:
+		patchedDocument, err = jsonpatch.MergePatch(document, patch)
+	case jsonpatch.JSONPatchContentType:
+		patchedDocument, err = jsonpatch.Apply(document, patch)
+	default:
+		return nil, fmt.Errorf("unsupported patch content type %s", contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patchedPost models.Post
+	if err := request.ReadJSON(bytes.NewReader(patchedDocument), &patchedPost); err != nil {
+		return nil, fmt.Errorf("%w: %s", jsonpatch.ErrInvalidPatch, err.Error())
+	}
+
+	if patchedPost.ID != post.ID || patchedPost.Version != post.Version || patchedPost.UserID != post.UserID ||
+		!patchedPost.CreatedAt.Equal(post.CreatedAt) || !patchedPost.UpdatedAt.Equal(post.UpdatedAt) {
+		return nil, errReadOnlyPostField
+	}
+	return &patchedPost, nil
+}
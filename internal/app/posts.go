@@ -2,14 +2,19 @@ package app
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/fdemchenko/arcus/internal/api/request"
 	"github.com/fdemchenko/arcus/internal/api/response"
+	"github.com/fdemchenko/arcus/internal/jsonpatch"
 	"github.com/fdemchenko/arcus/internal/models"
 	"github.com/fdemchenko/arcus/internal/repositories/postgres"
+	"github.com/fdemchenko/arcus/internal/services"
 	"github.com/fdemchenko/arcus/internal/validator"
 )
 
@@ -33,6 +38,7 @@ func (app *Application) createPost(w http.ResponseWriter, r *http.Request) {
 		Title:   input.Title,
 		Content: input.Content,
 		Tags:    input.Tags,
+		UserID:  app.contextGetUser(r).ID,
 	}
 
 	v := validator.New()
@@ -42,7 +48,7 @@ func (app *Application) createPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postID, err := app.postsService.Create(post)
+	postID, err := app.postsService.Create(r.Context(), post)
 	if err != nil {
 		logger.Error("failed to create post", slog.String("err", err.Error()))
 		response.SendServerError(w)
@@ -58,14 +64,64 @@ func (app *Application) getPosts(w http.ResponseWriter, r *http.Request) {
 	const op = "app.routes.getPosts"
 	logger := app.logger.With(slog.String("op", op))
 
-	posts, err := app.postsService.GetAll()
+	qs := r.URL.Query()
+	v := validator.New()
+
+	var filter models.PostsFilter
+	filter.Tags = models.NormalizeTags(request.ReadCSV(qs, "tags", []string{}))
+	filter.Query = strings.TrimSpace(request.ReadString(qs, "q", ""))
+	filter.Highlight = request.ReadBool(qs, "highlight", false, v)
+	filter.Page = request.ReadInt(qs, "page", 1, v)
+	filter.PageSize = request.ReadInt(qs, "page_size", 20, v)
+	filter.Sort = request.ReadString(qs, "sort", "id")
+	filter.SortSafelist = models.PostsSortSafelist
+	if filter.Query != "" {
+		// search results are ordered by relevance unless other order is requested
+		filter.Sort = request.ReadString(qs, "sort", "-rank")
+		filter.SortSafelist = models.PostsSearchSortSafelist
+	}
+
+	cursorMode := qs.Has("cursor")
+	if cursorMode {
+		v.Check(!qs.Has("page"), "page", "must not be used together with cursor")
+		v.Check(!qs.Has("sort"), "sort", "must not be used together with cursor")
+	}
+
+	if filter.Validate(v); !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	if cursorMode {
+		posts, nextCursor, err := app.postsService.GetAllByCursor(r.Context(), filter, qs.Get("cursor"))
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCursor) {
+				response.SendError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			logger.Error("failed to get posts by cursor", slog.String("err", err.Error()))
+			response.SendServerError(w)
+			return
+		}
+
+		var next *string
+		if nextCursor != "" {
+			next = &nextCursor
+		}
+		if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"posts": posts, "next_cursor": next}); err != nil {
+			response.SendServerError(w)
+		}
+		return
+	}
+
+	posts, metadata, err := app.postsService.GetAll(r.Context(), filter)
 	if err != nil {
 		logger.Error("failed to get all posts", slog.String("err", err.Error()))
 		response.SendServerError(w)
 		return
 	}
 
-	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"posts": posts}); err != nil {
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"posts": posts, "metadata": metadata}); err != nil {
 		response.SendServerError(w)
 	}
 }
@@ -80,7 +136,7 @@ func (app *Application) getPostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := app.postsService.GetByID(id)
+	post, err := app.postsService.GetByID(r.Context(), id)
 	if err != nil {
 		logger.Error("failed to retrieve post from DB", slog.String("err", err.Error()))
 		if errors.Is(err, postgres.ErrPostDoesNotExist) {
@@ -91,6 +147,12 @@ func (app *Application) getPostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", postETag(*post))
+	if isNotModified(r, *post) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	response.WriteJSON(w, http.StatusOK, response.Envelope{"post": post})
 }
 
@@ -104,9 +166,9 @@ func (app *Application) deletePostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deletedID, err := app.postsService.DeleteByID(id)
+	post, err := app.postsService.GetByID(r.Context(), id)
 	if err != nil {
-		logger.Error("failed to delete post from DB", slog.String("err", err.Error()))
+		logger.Error("failed to get post from DB", slog.String("err", err.Error()))
 		if errors.Is(err, postgres.ErrPostDoesNotExist) {
 			response.SendError(w, http.StatusNotFound, err.Error())
 			return
@@ -115,7 +177,30 @@ func (app *Application) deletePostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.WriteJSON(w, http.StatusOK, response.Envelope{"post_id": deletedID})
+	if post.UserID != app.contextGetUser(r).ID {
+		response.SendNotPermittedError(w)
+		return
+	}
+
+	if !app.checkIfMatch(w, r, *post) {
+		return
+	}
+
+	// version guards against modifications made since the post was read
+	deletedID, err := app.postsService.DeleteByID(r.Context(), id, post.Version)
+	if err != nil {
+		logger.Error("failed to delete post from DB", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrEditConflict) {
+			app.sendEditConflictError(w, r, err)
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"post_id": deletedID}); err != nil {
+		response.SendServerError(w)
+	}
 }
 
 func (app *Application) updatePost(w http.ResponseWriter, r *http.Request) {
@@ -128,7 +213,7 @@ func (app *Application) updatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = app.postsService.GetByID(id)
+	existingPost, err := app.postsService.GetByID(r.Context(), id)
 	if err != nil {
 		logger.Error("failed to get post from DB", slog.String("err", err.Error()))
 		if errors.Is(err, postgres.ErrPostDoesNotExist) {
@@ -139,6 +224,15 @@ func (app *Application) updatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if existingPost.UserID != app.contextGetUser(r).ID {
+		response.SendNotPermittedError(w)
+		return
+	}
+
+	if !app.checkIfMatch(w, r, *existingPost) {
+		return
+	}
+
 	var input struct {
 		Title   string   `json:"title"`
 		Content *string  `json:"content"`
@@ -153,8 +247,10 @@ func (app *Application) updatePost(w http.ResponseWriter, r *http.Request) {
 		Title:   input.Title,
 		Content: input.Content,
 		Tags:    input.Tags,
+		UserID:  existingPost.UserID,
 	}
 	post.ID = id
+	post.Version = existingPost.Version
 
 	v := validator.New()
 	if post.Validate(v); !v.IsValid() {
@@ -162,13 +258,19 @@ func (app *Application) updatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.postsService.UpdateByID(post)
+	err = app.postsService.UpdateByID(r.Context(), post)
 	if err != nil {
 		logger.Error("failed to update post", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrEditConflict) {
+			app.sendEditConflictError(w, r, err)
+			return
+		}
 		response.SendServerError(w)
 		return
 	}
 
+	post.Version++
+	w.Header().Set("ETag", postETag(post))
 	response.WriteJSON(w, http.StatusOK, response.Envelope{"post_id": id})
 }
 
@@ -182,7 +284,7 @@ func (app *Application) partialPostUpdate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	post, err := app.postsService.GetByID(id)
+	post, err := app.postsService.GetByID(r.Context(), id)
 	if err != nil {
 		logger.Error("failed to get post from DB", slog.String("err", err.Error()))
 		if errors.Is(err, postgres.ErrPostDoesNotExist) {
@@ -193,31 +295,71 @@ func (app *Application) partialPostUpdate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	input := struct {
-		Title   *string  `json:"title"`
-		Content *string  `json:"content"`
-		Tags    []string `json:"tags"`
-	}{}
-
-	err = request.ReadJSON(r.Body, &input)
-	if err != nil {
-		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+	if post.UserID != app.contextGetUser(r).ID {
+		response.SendNotPermittedError(w)
 		return
 	}
 
-	if input.Title != nil {
-		post.Title = *input.Title
+	if !app.checkIfMatch(w, r, *post) {
+		return
 	}
 
-	if input.Content != nil {
-		post.Content = input.Content
-		if *input.Content == "" {
-			post.Content = nil
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, "malformed Content-Type header")
+			return
 		}
 	}
 
-	if input.Tags != nil {
-		post.Tags = input.Tags
+	switch mediaType {
+	case jsonpatch.MergePatchContentType, jsonpatch.JSONPatchContentType:
+		post, err = applyPostPatch(*post, mediaType, r.Body)
+		if err != nil {
+			logger.Error("failed to apply patch", slog.String("err", err.Error()))
+			switch {
+			case errors.Is(err, jsonpatch.ErrTestFailed):
+				response.SendError(w, http.StatusConflict, err.Error())
+			case errors.Is(err, jsonpatch.ErrPathNotFound), errors.Is(err, errReadOnlyPostField):
+				response.SendError(w, http.StatusUnprocessableEntity, err.Error())
+			default:
+				response.SendError(w, http.StatusBadRequest, err.Error())
+			}
+			return
+		}
+	case "application/json":
+		input := struct {
+			Title   *string  `json:"title"`
+			Content *string  `json:"content"`
+			Tags    []string `json:"tags"`
+		}{}
+
+		err = request.ReadJSON(r.Body, &input)
+		if err != nil {
+			response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+			return
+		}
+
+		if input.Title != nil {
+			post.Title = *input.Title
+		}
+
+		if input.Content != nil {
+			post.Content = input.Content
+			if *input.Content == "" {
+				post.Content = nil
+			}
+		}
+
+		if input.Tags != nil {
+			post.Tags = input.Tags
+		}
+	default:
+		message := fmt.Sprintf("unsupported Content-Type, use application/json, %s or %s",
+			jsonpatch.MergePatchContentType, jsonpatch.JSONPatchContentType)
+		response.SendError(w, http.StatusUnsupportedMediaType, message)
+		return
 	}
 
 	v := validator.New()
@@ -227,17 +369,87 @@ func (app *Application) partialPostUpdate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.postsService.UpdateByID(*post)
+	err = app.postsService.UpdateByID(r.Context(), *post)
 	if err != nil {
 		if errors.Is(err, postgres.ErrEditConflict) {
-			response.SendError(w, http.StatusConflict, err.Error())
+			app.sendEditConflictError(w, r, err)
 			return
 		}
 		response.SendServerError(w)
 		return
 	}
 
+	post.Version++
+	w.Header().Set("ETag", postETag(*post))
 	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"post": *post}); err != nil {
 		response.SendServerError(w)
 	}
 }
+
+func (app *Application) getTrash(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.getTrash"
+	logger := app.logger.With(slog.String("op", op))
+
+	posts, err := app.postsService.GetTrash(r.Context(), app.contextGetUser(r).ID)
+	if err != nil {
+		logger.Error("failed to get deleted posts", slog.String("err", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"posts": posts}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) restorePost(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.restorePost"
+	logger := app.logger.With(slog.String("op", op))
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	post, err := app.postsService.GetDeletedByID(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get deleted post from DB", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrPostDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	if post.UserID != app.contextGetUser(r).ID {
+		response.SendNotPermittedError(w)
+		return
+	}
+
+	err = app.postsService.Restore(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to restore post", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrPostDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"post_id": id}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+// sendEditConflictError reports concurrent modification, which is a failed precondition
+// for conditional requests.
+func (app *Application) sendEditConflictError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Header.Get("If-Match") != "" {
+		response.SendPreconditionFailedError(w)
+		return
+	}
+	response.SendError(w, http.StatusConflict, err.Error())
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/fdemchenko/arcus/internal/api/request"
+	"github.com/fdemchenko/arcus/internal/api/response"
+	"github.com/fdemchenko/arcus/internal/diff"
+	"github.com/fdemchenko/arcus/internal/repositories/postgres"
+	"github.com/fdemchenko/arcus/internal/validator"
+)
+
+func (app *Application) getPostRevisions(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.getPostRevisions"
+	logger := app.logger.With(slog.String("op", op))
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	_, err = app.postsService.GetByID(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get post from DB", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrPostDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	revisions, err := app.postsService.GetRevisions(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get post revisions", slog.String("err", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"revisions": revisions}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) getPostRevision(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.getPostRevision"
+	logger := app.logger.With(slog.String("op", op))
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	post, err := app.postsService.GetByID(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get post from DB", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrPostDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	revision, err := app.postsService.GetRevision(r.Context(), *post, version)
+	if err != nil {
+		logger.Error("failed to get post revision", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrRevisionDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"revision": revision}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) diffPostRevisions(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.diffPostRevisions"
+	logger := app.logger.With(slog.String("op", op))
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	post, err := app.postsService.GetByID(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get post from DB", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrPostDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	qs := r.URL.Query()
+	v := validator.New()
+	// never edited post has only version 0, which yields an empty diff
+	from := request.ReadInt(qs, "from", max(post.Version-1, 0), v)
+	to := request.ReadInt(qs, "to", post.Version, v)
+	v.Check(from >= 0, "from", "must not be negative")
+	v.Check(to >= 0, "to", "must not be negative")
+	if !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	unifiedDiff, err := app.postsService.DiffRevisions(r.Context(), *post, from, to)
+	if err != nil {
+		logger.Error("failed to diff post revisions", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrRevisionDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, diff.ErrTooLarge) {
+			response.SendError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(unifiedDiff)); err != nil {
+		logger.Error("failed to write diff", slog.String("err", err.Error()))
+	}
+}
+
+func (app *Application) restorePostRevision(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.restorePostRevision"
+	logger := app.logger.With(slog.String("op", op))
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil {
+		response.SendError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	post, err := app.postsService.GetByID(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get post from DB", slog.String("err", err.Error()))
+		if errors.Is(err, postgres.ErrPostDoesNotExist) {
+			response.SendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.SendServerError(w)
+		return
+	}
+
+	if post.UserID != app.contextGetUser(r).ID {
+		response.SendNotPermittedError(w)
+		return
+	}
+
+	if !app.checkIfMatch(w, r, *post) {
+		return
+	}
+
+	restoredPost, err := app.postsService.RestoreRevision(r.Context(), *post, version)
+	if err != nil {
+		logger.Error("failed to restore post revision", slog.String("err", err.Error()))
+		switch {
+		case errors.Is(err, postgres.ErrRevisionDoesNotExist):
+			response.SendError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, postgres.ErrEditConflict):
+			app.sendEditConflictError(w, r, err)
+		default:
+			response.SendServerError(w)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", postETag(*restoredPost))
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"post": restoredPost}); err != nil {
+		response.SendServerError(w)
+	}
+}
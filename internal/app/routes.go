@@ -11,15 +11,39 @@ func (app *Application) Routes() http.Handler {
 
 	mux.HandleFunc("POST /auth/register", app.registerUser)
 	mux.HandleFunc("PUT /auth/activate", app.activateUser)
+	mux.HandleFunc("GET /auth/activate", app.activateUserByLink)
 	mux.HandleFunc("POST /auth/resend-activation-token", app.resendActivationToken)
+	mux.HandleFunc("POST /auth/login", app.loginUser)
+	mux.HandleFunc("POST /auth/password-reset", app.requestPasswordReset)
+	mux.HandleFunc("PUT /auth/password", app.resetPassword)
 
-	mux.HandleFunc("POST /posts", app.createPost)
+	mux.HandleFunc("POST /posts", app.requireActivatedUser(app.createPost))
 	mux.HandleFunc("GET /posts", app.getPosts)
 	mux.HandleFunc("GET /posts/{id}", app.getPostByID)
-	mux.HandleFunc("DELETE /posts/{id}", app.deletePostByID)
-	mux.HandleFunc("PUT /posts/{id}", app.updatePost)
-	mux.HandleFunc("PATCH /posts/{id}", app.partialPostUpdate)
-	middlewares := alice.New(app.RecoveryMiddleware, app.LoggingMiddleware)
+	mux.HandleFunc("DELETE /posts/{id}", app.requireActivatedUser(app.deletePostByID))
+	mux.HandleFunc("PUT /posts/{id}", app.requireActivatedUser(app.updatePost))
+	mux.HandleFunc("PATCH /posts/{id}", app.requireActivatedUser(app.partialPostUpdate))
+	mux.HandleFunc("GET /posts/trash", app.requireAuthenticatedUser(app.getTrash))
+	mux.HandleFunc("POST /posts/{id}/restore", app.requireActivatedUser(app.restorePost))
+	mux.HandleFunc("GET /posts/{id}/revisions", app.getPostRevisions)
+	mux.HandleFunc("GET /posts/{id}/revisions/{version}", app.getPostRevision)
+	mux.HandleFunc("GET /posts/{id}/diff", app.diffPostRevisions)
+	mux.HandleFunc("POST /posts/{id}/revisions/{version}/restore", app.requireActivatedUser(app.restorePostRevision))
+
+	mux.HandleFunc("GET /tags", app.getTags)
+	mux.HandleFunc("GET /tags/{tag}/posts", app.getTagPosts)
+	mux.HandleFunc("POST /tags/merge", app.requireAdminUser(app.mergeTags))
+
+	mux.HandleFunc("GET /admin/email-templates", app.requireAdminUser(app.getEmailTemplates))
+	mux.HandleFunc("POST /admin/email-templates/{name}/preview", app.requireAdminUser(app.previewEmailTemplate))
+	mux.HandleFunc("POST /admin/email-templates/{name}/test-send", app.requireAdminUser(app.testSendEmailTemplate))
+
+	if app.mailbox != nil {
+		mux.HandleFunc("GET /dev/mailbox", app.getMailbox)
+		mux.HandleFunc("DELETE /dev/mailbox", app.clearMailbox)
+	}
+
+	middlewares := alice.New(app.RecoveryMiddleware, app.LoggingMiddleware, app.Authenticate)
 
 	return middlewares.Then(mux)
 }
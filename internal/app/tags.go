@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/fdemchenko/arcus/internal/api/request"
+	"github.com/fdemchenko/arcus/internal/api/response"
+	"github.com/fdemchenko/arcus/internal/models"
+	"github.com/fdemchenko/arcus/internal/validator"
+)
+
+func (app *Application) getTags(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.getTags"
+	logger := app.logger.With(slog.String("op", op))
+
+	tags, err := app.tagsService.GetAll(r.Context())
+	if err != nil {
+		logger.Error("failed to get tags", slog.String("err", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"tags": tags}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) getTagPosts(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.getTagPosts"
+	logger := app.logger.With(slog.String("op", op))
+
+	tag := models.NormalizeTag(r.PathValue("tag"))
+
+	qs := r.URL.Query()
+	v := validator.New()
+
+	var filter models.PostsFilter
+	filter.Tags = []string{tag}
+	filter.Page = request.ReadInt(qs, "page", 1, v)
+	filter.PageSize = request.ReadInt(qs, "page_size", 20, v)
+	filter.Sort = request.ReadString(qs, "sort", "id")
+	filter.SortSafelist = models.PostsSortSafelist
+
+	if filter.Validate(v); !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	posts, metadata, err := app.postsService.GetAll(r.Context(), filter)
+	if err != nil {
+		logger.Error("failed to get tag posts", slog.String("err", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"posts": posts, "metadata": metadata}); err != nil {
+		response.SendServerError(w)
+	}
+}
+
+func (app *Application) mergeTags(w http.ResponseWriter, r *http.Request) {
+	const op = "app.routes.mergeTags"
+	logger := app.logger.With(slog.String("op", op))
+
+	var input struct {
+		Sources []string `json:"sources"`
+		Target  string   `json:"target"`
+	}
+	err := request.ReadJSON(r.Body, &input)
+	if err != nil {
+		logger.Error("failed to decode JSON user input", slog.String("error", err.Error()))
+		response.SendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	target := models.NormalizeTag(input.Target)
+	sources := models.NormalizeTags(input.Sources)
+
+	v := validator.New()
+	v.Check(len(sources) > 0, "sources", "must contain at least one tag")
+	for _, source := range sources {
+		v.Check(source != "", "sources", "must not contain empty tags")
+	}
+	v.Check(target != "", "target", "must not be empty")
+	v.Check(utf8.RuneCountInString(target) <= models.PostTagMaxLength, "target", fmt.Sprintf("must not be greater than %d characters long", models.PostTagMaxLength))
+	if !v.IsValid() {
+		response.SendError(w, http.StatusBadRequest, v.Errors)
+		return
+	}
+
+	updatedPosts, err := app.tagsService.Merge(r.Context(), sources, target)
+	if err != nil {
+		logger.Error("failed to merge tags", slog.String("err", err.Error()))
+		response.SendServerError(w)
+		return
+	}
+
+	if err := response.WriteJSON(w, http.StatusOK, response.Envelope{"tag": target, "updated_posts": updatedPosts}); err != nil {
+		response.SendServerError(w)
+	}
+}
@@ -10,30 +10,90 @@ import (
 )
 
 type Config struct {
-	Storage            StorageConfig `yaml:"storage"`
-	HTTPServer         HTTPConfig    `yaml:"http-server"`
-	SMTPMailer         SMTPConfig    `yaml:"smtp-mailer"`
-	ActivationTokenTTL time.Duration `yaml:"activation-token-ttl" env-default:"2h"`
-	RabbitMQConnString string        `yaml:"rabbitmq-conn-string" env-required:"true"`
-	OpenAIKEY          string        `yaml:"openai-key" env:"ARCUS_OPENAI_KEY" env-required:"true"`
-	Env                string        `yaml:"env" env-default:"development"`
+	Storage                StorageConfig       `yaml:"storage"`
+	HTTPServer             HTTPConfig          `yaml:"http-server"`
+	SMTPMailer             SMTPConfig          `yaml:"smtp-mailer"`
+	MailTransport          MailTransportConfig `yaml:"mail-transport"`
+	MailTemplates          MailTemplatesConfig `yaml:"mail-templates"`
+	Frontend               FrontendConfig      `yaml:"frontend"`
+	Trash                  TrashConfig         `yaml:"trash"`
+	Outbox                 OutboxConfig        `yaml:"outbox"`
+	MailConsumer           MailConsumerConfig  `yaml:"mail-consumer"`
+	ActivationTokenTTL     time.Duration       `yaml:"activation-token-ttl" env-default:"2h"`
+	AuthenticationTokenTTL time.Duration       `yaml:"authentication-token-ttl" env-default:"24h"`
+	PasswordResetTokenTTL  time.Duration       `yaml:"password-reset-token-ttl" env-default:"45m"`
+	RabbitMQConnString     string              `yaml:"rabbitmq-conn-string" env-required:"true"`
+	CursorSigningKey       string              `yaml:"cursor-signing-key" env:"ARCUS_CURSOR_SIGNING_KEY" env-required:"true"`
+	OpenAIKEY              string              `yaml:"openai-key" env:"ARCUS_OPENAI_KEY" env-required:"true"`
+	Env                    string              `yaml:"env" env-default:"development"`
 }
 
 type HTTPConfig struct {
-	Host string `yaml:"host" env-default:"localhost"`
-	Port int    `yaml:"port" env-default:"8080"`
+	Host            string        `yaml:"host" env-default:"localhost"`
+	Port            int           `yaml:"port" env-default:"8080"`
+	RequireIfMatch  bool          `yaml:"require-if-match" env-default:"false"`
+	ShutdownTimeout time.Duration `yaml:"shutdown-timeout" env-default:"15s"`
 }
 
 type SMTPConfig struct {
-	Host          string `yaml:"host" env-rquired:"true"`
-	Port          int    `yaml:"port" env-default:"25"`
-	Username      string `yaml:"username" env-rquired:"true"`
-	Password      string `yaml:"password" env-rquired:"true"`
-	SenderAddress string `yaml:"sender-address" env-required:"true"`
+	Host               string `yaml:"host" env-rquired:"true"`
+	Port               int    `yaml:"port" env-default:"25"`
+	Username           string `yaml:"username" env-rquired:"true"`
+	Password           string `yaml:"password" env-rquired:"true"`
+	SenderAddress      string `yaml:"sender-address" env-required:"true"`
+	MaxIdleConnections int    `yaml:"max-idle-connections" env-default:"4"`
+}
+
+// MailTransportConfig selects how emails are delivered: "smtp", "file" or "memory".
+type MailTransportConfig struct {
+	Kind           string `yaml:"kind" env-default:"smtp"`
+	Directory      string `yaml:"directory" env-default:"./mailbox"`
+	MemoryCapacity int    `yaml:"memory-capacity" env-default:"100"`
+}
+
+// MailTemplatesConfig enables reloading templates from Directory on change, works only in development.
+type MailTemplatesConfig struct {
+	HotReload      bool          `yaml:"hot-reload" env-default:"false"`
+	Directory      string        `yaml:"directory" env-default:"./templates"`
+	ReloadInterval time.Duration `yaml:"reload-interval" env-default:"1s"`
+}
+
+// FrontendConfig describes where users are sent from emails. Activation link points to
+// BaseURL + ActivationPath, which must reach GET /auth/activate of the API, and after
+// activation users are redirected to success or failure URL.
+type FrontendConfig struct {
+	BaseURL              string `yaml:"base-url" env:"ARCUS_FRONTEND_BASE_URL" env-default:"http://localhost:8080"`
+	ActivationPath       string `yaml:"activation-path" env-default:"/auth/activate"`
+	ActivationSuccessURL string `yaml:"activation-success-url" env-default:"http://localhost:8080/"`
+	ActivationFailureURL string `yaml:"activation-failure-url" env-default:"http://localhost:8080/"`
+}
+
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge-interval" env-default:"1h"`
+}
+
+type OutboxConfig struct {
+	PollInterval  time.Duration `yaml:"poll-interval" env-default:"1s"`
+	BatchSize     int           `yaml:"batch-size" env-default:"100"`
+	MaxAttempts   int           `yaml:"max-attempts" env-default:"15"`
+	Retention     time.Duration `yaml:"retention" env-default:"168h"`
+	PurgeInterval time.Duration `yaml:"purge-interval" env-default:"1h"`
+}
+
+type MailConsumerConfig struct {
+	Workers        int           `yaml:"workers" env-default:"4"`
+	Prefetch       int           `yaml:"prefetch" env-default:"16"`
+	RateLimit      float64       `yaml:"rate-limit" env-default:"10"`
+	RateBurst      int           `yaml:"rate-burst" env-default:"10"`
+	MaxAttempts    int           `yaml:"max-attempts" env-default:"5"`
+	RetryBaseDelay time.Duration `yaml:"retry-base-delay" env-default:"10s"`
+	RetryMaxDelay  time.Duration `yaml:"retry-max-delay" env-default:"10m"`
 }
 
 type StorageConfig struct {
-	DSN string `yaml:"dsn" env-required:"true" env:"ARCUS_POSTGRES_DSN"`
+	DSN          string        `yaml:"dsn" env-required:"true" env:"ARCUS_POSTGRES_DSN"`
+	QueryTimeout time.Duration `yaml:"query-timeout" env-default:"5s"`
 }
 
 func MustLoad() *Config {
@@ -45,18 +105,62 @@ func MustLoad() *Config {
 }
 
 func Load() (*Config, error) {
-	configPath, err := fetchConfigPath()
+	var cfg Config
+	err := Read(&cfg)
 	if err != nil {
 		return nil, err
 	}
-	var cfg Config
-	err = cleanenv.ReadConfig(configPath, &cfg)
-	if err != nil {
+	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
 	return &cfg, nil
 }
 
+// RabbitMQConfig is a subset of Config for tools which only talk to the message broker.
+type RabbitMQConfig struct {
+	RabbitMQConnString string `yaml:"rabbitmq-conn-string" env-required:"true"`
+}
+
+// Read reads configuration file into cfg, which may describe only part of the settings.
+func Read(cfg any) error {
+	configPath, err := fetchConfigPath()
+	if err != nil {
+		return err
+	}
+	return cleanenv.ReadConfig(configPath, cfg)
+}
+
+// validate checks values which would otherwise make background workers panic.
+func (cfg *Config) validate() error {
+	if cfg.Trash.PurgeInterval <= 0 {
+		return fmt.Errorf("trash purge-interval must be positive, got %s", cfg.Trash.PurgeInterval)
+	}
+	if cfg.Outbox.PollInterval <= 0 {
+		return fmt.Errorf("outbox poll-interval must be positive, got %s", cfg.Outbox.PollInterval)
+	}
+	// with zero batch size the relay never leaves its drain loop
+	if cfg.Outbox.BatchSize <= 0 {
+		return fmt.Errorf("outbox batch-size must be positive, got %d", cfg.Outbox.BatchSize)
+	}
+	if cfg.Outbox.MaxAttempts <= 0 {
+		return fmt.Errorf("outbox max-attempts must be positive, got %d", cfg.Outbox.MaxAttempts)
+	}
+	if cfg.Outbox.PurgeInterval <= 0 {
+		return fmt.Errorf("outbox purge-interval must be positive, got %s", cfg.Outbox.PurgeInterval)
+	}
+	if cfg.MailConsumer.Workers <= 0 {
+		return fmt.Errorf("mail-consumer workers must be positive, got %d", cfg.MailConsumer.Workers)
+	}
+	// zero prefetch means no limit, so the broker would push the whole queue to the consumer
+	if cfg.MailConsumer.Prefetch <= 0 {
+		return fmt.Errorf("mail-consumer prefetch must be positive, got %d", cfg.MailConsumer.Prefetch)
+	}
+	if cfg.MailTemplates.HotReload && cfg.MailTemplates.ReloadInterval <= 0 {
+		return fmt.Errorf("mail-templates reload-interval must be positive, got %s", cfg.MailTemplates.ReloadInterval)
+	}
+	return nil
+}
+
 func fetchConfigPath() (string, error) {
 	var configPath string
 	flag.StringVar(&configPath, "config-path", os.Getenv("ARCUS_CONFIG_PATH"), "App configuration file path")
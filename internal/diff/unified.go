@@ -0,0 +1,206 @@
+// Package diff produces line-based diffs in unified format.
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const DefaultContextLines = 3
+
+// MaxLines limits total amount of lines of compared texts, as diff time grows with
+// their size multiplied by the amount of changes.
+const MaxLines = 20000
+
+var ErrTooLarge = errors.New("texts are too large to diff")
+
+type edit struct {
+	kind byte // ' ' - line is kept, '-' - deleted, '+' - inserted
+	text string
+	// amount of lines of both texts preceding this edit
+	fromLine, toLine int
+}
+
+// Unified returns unified diff of two texts, fromName and toName are used in file headers.
+// Empty string is returned if texts are equal.
+func Unified(fromName, toName, from, to string, contextLines int) (string, error) {
+	fromLines, toLines := splitLines(from), splitLines(to)
+	if len(fromLines)+len(toLines) > MaxLines {
+		return "", ErrTooLarge
+	}
+	edits := computeEdits(fromLines, toLines)
+
+	var sb strings.Builder
+	for start := 0; start < len(edits); {
+		if edits[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		// extend hunk while changes are separated by no more than 2*contextLines kept lines
+		lastChange := start
+		for i := start; i < len(edits) && i-lastChange <= 2*contextLines; i++ {
+			if edits[i].kind != ' ' {
+				lastChange = i
+			}
+		}
+		hunkStart := max(0, start-contextLines)
+		hunkEnd := min(len(edits), lastChange+contextLines+1)
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&sb, edits[hunkStart:hunkEnd])
+		start = hunkEnd
+	}
+	return sb.String(), nil
+}
+
+func writeHunk(sb *strings.Builder, hunk []edit) {
+	fromStart, toStart := hunk[0].fromLine+1, hunk[0].toLine+1
+	fromLen, toLen := 0, 0
+	for _, e := range hunk {
+		if e.kind != '+' {
+			fromLen++
+		}
+		if e.kind != '-' {
+			toLen++
+		}
+	}
+	// empty range is denoted by the line preceding it
+	if fromLen == 0 {
+		fromStart--
+	}
+	if toLen == 0 {
+		toStart--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", fromStart, fromLen, toStart, toLen)
+	for _, e := range hunk {
+		sb.WriteByte(e.kind)
+		sb.WriteString(e.text)
+		sb.WriteByte('\n')
+	}
+}
+
+// computeEdits finds the shortest edit script with Myers' linear space algorithm.
+func computeEdits(from, to []string) []edit {
+	d := differ{a: from, b: to, edits: make([]edit, 0, len(from)+len(to))}
+	d.compare(0, len(from), 0, len(to))
+	return d.edits
+}
+
+type differ struct {
+	a, b             []string
+	edits            []edit
+	fromLine, toLine int
+}
+
+func (d *differ) appendEdit(kind byte, text string) {
+	d.edits = append(d.edits, edit{kind: kind, text: text, fromLine: d.fromLine, toLine: d.toLine})
+	if kind != '+' {
+		d.fromLine++
+	}
+	if kind != '-' {
+		d.toLine++
+	}
+}
+
+// compare appends edits turning a[aLo:aHi] into b[bLo:bHi]. Common prefix and suffix are
+// stripped first, the rest is split by the middle snake and compared recursively.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.appendEdit(' ', d.a[aLo])
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	x, y := aLo, bLo
+	if aLo < aHi && bLo < bHi {
+		x, y = d.middleSnake(aLo, aHi, bLo, bHi)
+	}
+	// split point equal to a corner would not make the problem smaller
+	if (x == aLo && y == bLo) || (x == aHi && y == bHi) {
+		for i := aLo; i < aHi; i++ {
+			d.appendEdit('-', d.a[i])
+		}
+		for j := bLo; j < bHi; j++ {
+			d.appendEdit('+', d.b[j])
+		}
+	} else {
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+
+	for i := aHi; i < aHi+suffix; i++ {
+		d.appendEdit(' ', d.a[i])
+	}
+}
+
+// middleSnake returns a point lying on the shortest edit path, where forward and
+// reverse searches meet. Both vectors hold the furthest reaching x for each diagonal.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	reverse := make([]int, 2*offset+1)
+
+	for step := 0; step <= maxD; step++ {
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			// reverse diagonal matching forward diagonal k is delta-k
+			if reverseK := delta - k; odd && reverseK >= -(step-1) && reverseK <= step-1 && x+reverse[offset+reverseK] >= n {
+				return aLo + x, bLo + y
+			}
+		}
+
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && reverse[offset+k-1] < reverse[offset+k+1]) {
+				x = reverse[offset+k+1]
+			} else {
+				x = reverse[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			reverse[offset+k] = x
+
+			if forwardK := delta - k; !odd && forwardK >= -step && forwardK <= step && x+forward[offset+forwardK] >= n {
+				return aHi - x, bHi - y
+			}
+		}
+	}
+	// unreachable, searches always meet within maxD steps
+	return aLo, bLo
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
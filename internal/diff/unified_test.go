@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"both empty", "", "", ""},
+		{"insert into empty", "", "a\n", "--- x\n+++ y\n@@ -0,0 +1,1 @@\n+a\n"},
+		{"delete all", "a\nb\n", "", "--- x\n+++ y\n@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{"replace middle line", "a\nb\nc\n", "a\nB\nc\n", "--- x\n+++ y\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unified("x", "y", tt.from, tt.to, DefaultContextLines)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedTooLarge(t *testing.T) {
+	large := strings.Repeat("line\n", MaxLines+1)
+	if _, err := Unified("x", "y", large, "", DefaultContextLines); err != ErrTooLarge {
+		t.Errorf("got %v, want %v", err, ErrTooLarge)
+	}
+}
+
+// TestComputeEditsIsMinimal checks edit scripts against the quadratic LCS solution.
+func TestComputeEditsIsMinimal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rnd.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rnd.Intn(3)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		from, to := randomLines(), randomLines()
+		edits := computeEdits(from, to)
+
+		var gotFrom, gotTo []string
+		changes := 0
+		for _, e := range edits {
+			if e.kind != '+' {
+				gotFrom = append(gotFrom, e.text)
+			}
+			if e.kind != '-' {
+				gotTo = append(gotTo, e.text)
+			}
+			if e.kind != ' ' {
+				changes++
+			}
+		}
+		if strings.Join(gotFrom, "|") != strings.Join(from, "|") || strings.Join(gotTo, "|") != strings.Join(to, "|") {
+			t.Fatalf("edits of %q -> %q do not reproduce texts", from, to)
+		}
+		if want := len(from) + len(to) - 2*lcsLength(from, to); changes != want {
+			t.Fatalf("edits of %q -> %q have %d changes, want %d", from, to, changes, want)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs[0][0]
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+const TopicSendEmail = "send_email"
+
+// OutboxMessage is a message stored in DB within business transaction,
+// which is later dispatched to the message broker.
+type OutboxMessage struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
@@ -12,6 +12,7 @@ import (
 const PostTitleMaxLength = 70
 const PostTagMaxLength = 40
 const PostTagsMaxAmount = 6
+const PostSearchQueryMaxLength = 200
 
 type Post struct {
 	ID        int       `json:"id"`
@@ -22,6 +23,11 @@ type Post struct {
 	UserID    int       `json:"user_id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set only for posts moved to trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Snippet holds HTML-escaped search match with matched words wrapped in <b> tags,
+	// it is set only for full-text search results
+	Snippet *string `json:"snippet,omitempty"`
 }
 
 func (p *Post) Validate(v validator.Validator) {
@@ -52,7 +58,31 @@ func (p *Post) preparePost() {
 	if p.Tags == nil {
 		p.Tags = make([]string, 0)
 	}
-	for i := range len(p.Tags) {
-		p.Tags[i] = strings.TrimSpace(p.Tags[i])
-	}
+	p.Tags = NormalizeTags(p.Tags)
+}
+
+var PostsSortSafelist = []string{"id", "title", "created_at", "updated_at", "-id", "-title", "-created_at", "-updated_at"}
+
+// PostsSearchSortSafelist extends PostsSortSafelist with relevance ordering,
+// which is available only when search query is provided.
+var PostsSearchSortSafelist = append([]string{"-rank"}, PostsSortSafelist...)
+
+type PostsFilter struct {
+	Tags      []string
+	Query     string
+	Highlight bool
+	Filters
+}
+
+func (f PostsFilter) Validate(v validator.Validator) {
+	v.Check(utf8.RuneCountInString(f.Query) <= PostSearchQueryMaxLength, "q", fmt.Sprintf("must not be greater than %d characters long", PostSearchQueryMaxLength))
+	v.Check(f.Query != "" || !f.Highlight, "highlight", "must be used together with search query")
+	f.Filters.Validate(v)
+}
+
+// PostsCursor points to the last post of a page in keyset pagination,
+// posts are ordered by (created_at, id) in descending order.
+type PostsCursor struct {
+	CreatedAt time.Time
+	ID        int
 }
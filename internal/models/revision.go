@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PostRevision is a snapshot of post state at a specific version.
+type PostRevision struct {
+	PostID    int       `json:"post_id"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Content   *string   `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func RevisionFromPost(post Post) PostRevision {
+	return PostRevision{
+		PostID:    post.ID,
+		Version:   post.Version,
+		Title:     post.Title,
+		Content:   post.Content,
+		Tags:      post.Tags,
+		CreatedAt: post.UpdatedAt,
+	}
+}
+
+// Text returns plain text representation of revision used for diffs.
+func (pr PostRevision) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "title: %s\n", pr.Title)
+	fmt.Fprintf(&sb, "tags: %s\n", strings.Join(pr.Tags, ", "))
+	if pr.Content != nil {
+		sb.WriteString("\n")
+		sb.WriteString(*pr.Content)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
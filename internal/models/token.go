@@ -8,7 +8,9 @@ import (
 )
 
 const (
-	ScopeActivation = "activation"
+	ScopeActivation     = "activation"
+	ScopeAuthentication = "authentication"
+	ScopePasswordReset  = "password-reset"
 )
 
 const TokenBytesLength = 18
@@ -41,3 +43,12 @@ func GenerateToken(scope string, ttl time.Duration, userID int) (*Token, error)
 	}
 	return &token, nil
 }
+
+// IsValidTokenPlaintext reports whether s looks like a token produced by GenerateToken.
+func IsValidTokenPlaintext(s string) bool {
+	if len(s) != TokenBytesLength*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
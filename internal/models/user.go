@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"slices"
 	"time"
 	"unicode/utf8"
 
@@ -11,16 +12,29 @@ import (
 const MaxUserNameLength = 50
 const MinPasswordLength = 6
 
+const DefaultLocale = "en"
+
+var SupportedLocales = []string{"en", "uk"}
+
 type User struct {
 	ID        int
 	Name      string
 	Email     string
 	Activated bool
+	IsAdmin   bool
+	// Locale is a preferred language of emails sent to the user
+	Locale    string
 	Password  Password
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+var AnonymousUser = &User{}
+
+func (user *User) IsAnonymous() bool {
+	return user == AnonymousUser
+}
+
 type Password struct {
 	Plain string
 	Hash  []byte
@@ -36,6 +50,7 @@ func (user *User) Validate(v validator.Validator) {
 	v.Check(user.Password.Plain != "", "password", "should not be empty")
 	v.Check(CheckUserPassword(user.Password.Plain), "password", fmt.Sprintf("password should be at least %d characters long", MinPasswordLength))
 
+	v.Check(slices.Contains(SupportedLocales, user.Locale), "locale", fmt.Sprintf("should be one of %v", SupportedLocales))
 }
 
 func CheckUserPassword(password string) bool {
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/fdemchenko/arcus/internal/models"
+)
+
+type OutboxRepository struct {
+	DB           DBTX
+	QueryTimeout time.Duration
+}
+
+func (or *OutboxRepository) Insert(ctx context.Context, message models.OutboxMessage) error {
+	ctx, cancel := withTimeout(ctx, or.QueryTimeout)
+	defer cancel()
+
+	query := `INSERT INTO outbox (topic, payload) VALUES ($1, $2)`
+	_, err := or.DB.ExecContext(ctx, query, message.Topic, message.Payload)
+	return err
+}
+
+// FetchPending returns a batch of messages due for dispatch. Within a transaction
+// returned rows stay locked, and rows locked by others are skipped, so several relays
+// can run concurrently.
+func (or *OutboxRepository) FetchPending(ctx context.Context, batchSize int, maxAttempts int) ([]models.OutboxMessage, error) {
+	ctx, cancel := withTimeout(ctx, or.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT id, topic, payload, attempts, created_at FROM outbox
+					WHERE dispatched_at IS NULL AND attempts < $1 AND next_attempt_at <= CURRENT_TIMESTAMP
+					ORDER BY id
+					LIMIT $2
+					FOR UPDATE SKIP LOCKED`
+	rows, err := or.DB.QueryContext(ctx, query, maxAttempts, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]models.OutboxMessage, 0)
+	for rows.Next() {
+		var message models.OutboxMessage
+		err := rows.Scan(&message.ID, &message.Topic, &message.Payload, &message.Attempts, &message.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// MarkDispatched also clears the payload, since it may hold secrets such as tokens
+// which must not outlive the dispatch.
+func (or *OutboxRepository) MarkDispatched(ctx context.Context, id int64) error {
+	ctx, cancel := withTimeout(ctx, or.QueryTimeout)
+	defer cancel()
+
+	query := `UPDATE outbox SET dispatched_at = CURRENT_TIMESTAMP, attempts = attempts + 1, payload = '{}'::jsonb
+					WHERE id = $1`
+	_, err := or.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (or *OutboxRepository) MarkFailed(ctx context.Context, id int64, dispatchErr string, nextAttemptAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, or.QueryTimeout)
+	defer cancel()
+
+	query := `UPDATE outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2 WHERE id = $3`
+	_, err := or.DB.ExecContext(ctx, query, dispatchErr, nextAttemptAt, id)
+	return err
+}
+
+// PurgeFinished deletes dispatched messages and messages which exhausted maxAttempts,
+// created before the given time. It returns amount of deleted messages.
+func (or *OutboxRepository) PurgeFinished(ctx context.Context, maxAttempts int, before time.Time) (int, error) {
+	ctx, cancel := withTimeout(ctx, or.QueryTimeout)
+	defer cancel()
+
+	query := `DELETE FROM outbox WHERE (dispatched_at IS NOT NULL OR attempts >= $1) AND created_at < $2`
+	result, err := or.DB.ExecContext(ctx, query, maxAttempts, before)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
@@ -1,32 +1,116 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/fdemchenko/arcus/internal/models"
+	"github.com/fdemchenko/arcus/internal/repositories"
 	"github.com/lib/pq"
 )
 
 var ErrPostDoesNotExist = errors.New("post does not exist")
 var ErrEditConflict = errors.New("entity was edited concurrently")
+var ErrRevisionDoesNotExist = errors.New("post revision does not exist")
+
+// ts_headline marks matches with control characters, which are stripped from the text beforehand,
+// so that the snippet can be HTML-escaped before the marks are turned into tags.
+var snippetHighlighter = strings.NewReplacer("\x02", "<b>", "\x03", "</b>")
 
 type PostsRepository struct {
-	DB *sql.DB
+	DB           DBTX
+	QueryTimeout time.Duration
 }
 
-func (pr *PostsRepository) Insert(post models.Post) (int, error) {
-	query := `INSERT INTO posts (title, content, tags)
-					VALUES ($1, $2, $3) RETURNING id`
+func (pr *PostsRepository) Insert(ctx context.Context, post models.Post) (int, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `INSERT INTO posts (title, content, tags, user_id)
+					VALUES ($1, $2, $3, $4) RETURNING id`
 	var newPostID int
-	err := pr.DB.QueryRow(query, post.Title, post.Content, pq.StringArray(post.Tags)).Scan(&newPostID)
+	err := pr.DB.QueryRowContext(ctx, query, post.Title, post.Content, pq.StringArray(post.Tags), post.UserID).Scan(&newPostID)
 	return newPostID, err
 }
 
-func (pr *PostsRepository) GetAll() ([]models.Post, error) {
-	query := `SELECT id, title, content, created_at, updated_at, tags, version FROM posts`
+func (pr *PostsRepository) GetAll(ctx context.Context, filter models.PostsFilter) ([]models.Post, models.Metadata, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	// posts created before ownership was introduced have no owner,
+	// snippet covers title as well, since matches there are ranked too
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, title, content, created_at, updated_at, tags, version, COALESCE(user_id, 0),
+					ts_rank(search_vector, search_query) AS rank,
+					CASE WHEN $5::boolean
+						THEN ts_headline('simple', translate(concat_ws(' ', title, content), E'\x02\x03', ''), search_query,
+							E'MaxFragments=2, StartSel=\x02, StopSel=\x03')
+					END
+					FROM posts, websearch_to_tsquery('simple', $4) search_query
+					WHERE deleted_at IS NULL AND tags @> $1 AND ($4 = '' OR search_vector @@ search_query)
+					ORDER BY %s %s, id ASC
+					LIMIT $2 OFFSET $3`, filter.SortColumn(), filter.SortDirection())
 
-	rows, err := pr.DB.Query(query)
+	args := []any{pq.StringArray(filter.Tags), filter.Limit(), filter.Offset(), filter.Query, filter.Highlight}
+	rows, err := pr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	posts := make([]models.Post, 0)
+
+	for rows.Next() {
+		var post models.Post
+		tags := pq.StringArray{}
+		var rank float64
+		err := rows.Scan(&totalRecords, &post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &tags, &post.Version, &post.UserID, &rank, &post.Snippet)
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+		post.Snippet = highlightSnippet(post.Snippet)
+		post.Tags = tags
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalculateMetadata(totalRecords, filter.Page, filter.PageSize)
+	return posts, metadata, nil
+}
+
+func (pr *PostsRepository) GetAllAfter(ctx context.Context, filter models.PostsFilter, after *models.PostsCursor) ([]models.Post, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	// keyset pagination: rows are located by index on (created_at, id) instead of OFFSET,
+	// so pages stay stable while new posts are inserted
+	query := `SELECT id, title, content, created_at, updated_at, tags, version, COALESCE(user_id, 0),
+					CASE WHEN $4::boolean
+						THEN ts_headline('simple', translate(concat_ws(' ', title, content), E'\x02\x03', ''), search_query,
+							E'MaxFragments=2, StartSel=\x02, StopSel=\x03')
+					END
+					FROM posts, websearch_to_tsquery('simple', $3) search_query
+					WHERE deleted_at IS NULL AND tags @> $1 AND ($3 = '' OR search_vector @@ search_query)
+					AND ($5::timestamptz IS NULL OR (created_at, id) < ($5, $6))
+					ORDER BY created_at DESC, id DESC
+					LIMIT $2`
+
+	var afterCreatedAt *time.Time
+	var afterID int
+	if after != nil {
+		afterCreatedAt = &after.CreatedAt
+		afterID = after.ID
+	}
+
+	args := []any{pq.StringArray(filter.Tags), filter.Limit(), filter.Query, filter.Highlight, afterCreatedAt, afterID}
+	rows, err := pr.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,24 +121,31 @@ func (pr *PostsRepository) GetAll() ([]models.Post, error) {
 	for rows.Next() {
 		var post models.Post
 		tags := pq.StringArray{}
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &tags, &post.Version)
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &tags, &post.Version, &post.UserID, &post.Snippet)
 		if err != nil {
 			return nil, err
 		}
+		post.Snippet = highlightSnippet(post.Snippet)
 		post.Tags = tags
 		posts = append(posts, post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
-func (pr *PostsRepository) GetByID(id int) (*models.Post, error) {
-	query := `SELECT id, title, content, created_at, updated_at, tags, version FROM posts
-					WHERE id = $1`
+func (pr *PostsRepository) GetByID(ctx context.Context, id int) (*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT id, title, content, created_at, updated_at, tags, version, COALESCE(user_id, 0) FROM posts
+					WHERE id = $1 AND deleted_at IS NULL`
 
 	var post models.Post
 
 	tags := pq.StringArray{}
-	err := pr.DB.QueryRow(query, id).Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &tags, &post.Version)
+	err := pr.DB.QueryRowContext(ctx, query, id).Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &tags, &post.Version, &post.UserID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrPostDoesNotExist
@@ -65,13 +156,18 @@ func (pr *PostsRepository) GetByID(id int) (*models.Post, error) {
 	return &post, nil
 }
 
-func (pr *PostsRepository) DeleteByID(id int) (int, error) {
-	query := `DELETE FROM posts WHERE id = $1 RETURNING id`
+// DeleteByID moves post to trash, if its version matches.
+func (pr *PostsRepository) DeleteByID(ctx context.Context, id int, version int) (int, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `UPDATE posts SET deleted_at = CURRENT_TIMESTAMP
+					WHERE id = $1 AND version = $2 AND deleted_at IS NULL RETURNING id`
 	var deletedID int
-	err := pr.DB.QueryRow(query, id).Scan(&deletedID)
+	err := pr.DB.QueryRowContext(ctx, query, id, version).Scan(&deletedID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return 0, ErrPostDoesNotExist
+			return 0, ErrEditConflict
 		}
 		return 0, err
 	}
@@ -79,13 +175,59 @@ func (pr *PostsRepository) DeleteByID(id int) (int, error) {
 	return deletedID, nil
 }
 
-func (pr *PostsRepository) UpdateByID(post models.Post) error {
+// InsertRevision stores current post state, if its version matches, in post_revisions.
+// The post row is locked until the end of transaction.
+func (pr *PostsRepository) InsertRevision(ctx context.Context, postID int, version int) error {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `INSERT INTO post_revisions (post_id, version, title, content, tags, created_at)
+					SELECT id, version, title, content, tags, updated_at FROM posts
+					WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+					FOR UPDATE`
+	result, err := pr.DB.ExecContext(ctx, query, postID, version)
+	if err != nil {
+		var pgError *pq.Error
+		if errors.As(err, &pgError) && pgError.Code == pq.ErrorCode(repositories.UniqueViolationErrorCode) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
+
+// InsertRevisionsByTags stores current state of posts having any of the tags in post_revisions.
+func (pr *PostsRepository) InsertRevisionsByTags(ctx context.Context, tags []string) error {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `INSERT INTO post_revisions (post_id, version, title, content, tags, created_at)
+					SELECT id, version, title, content, tags, updated_at FROM posts
+					WHERE tags && $1
+					FOR UPDATE`
+	_, err := pr.DB.ExecContext(ctx, query, pq.StringArray(tags))
+	return err
+}
+
+func (pr *PostsRepository) UpdateByID(ctx context.Context, post models.Post) error {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
 	query := `UPDATE posts 
 					 SET title = $1,
 					 content = $2,
 					 version = version + 1,
-					 tags = $3 WHERE id = $4 AND version = $5`
-	result, err := pr.DB.Exec(query, post.Title, post.Content, pq.StringArray(post.Tags), post.ID, post.Version)
+					 tags = $3 WHERE id = $4 AND version = $5 AND deleted_at IS NULL`
+	result, err := pr.DB.ExecContext(ctx, query, post.Title, post.Content, pq.StringArray(post.Tags), post.ID, post.Version)
 	if err != nil {
 		return err
 	}
@@ -100,3 +242,156 @@ func (pr *PostsRepository) UpdateByID(post models.Post) error {
 	}
 	return nil
 }
+
+func (pr *PostsRepository) GetRevisions(ctx context.Context, postID int) ([]models.PostRevision, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT post_id, version, title, content, tags, created_at FROM post_revisions
+					WHERE post_id = $1
+					ORDER BY version DESC`
+
+	rows, err := pr.DB.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]models.PostRevision, 0)
+
+	for rows.Next() {
+		var revision models.PostRevision
+		tags := pq.StringArray{}
+		err := rows.Scan(&revision.PostID, &revision.Version, &revision.Title, &revision.Content, &tags, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revision.Tags = tags
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (pr *PostsRepository) GetRevision(ctx context.Context, postID int, version int) (*models.PostRevision, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT post_id, version, title, content, tags, created_at FROM post_revisions
+					WHERE post_id = $1 AND version = $2`
+
+	var revision models.PostRevision
+	tags := pq.StringArray{}
+	err := pr.DB.QueryRowContext(ctx, query, postID, version).
+		Scan(&revision.PostID, &revision.Version, &revision.Title, &revision.Content, &tags, &revision.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRevisionDoesNotExist
+		}
+		return nil, err
+	}
+	revision.Tags = tags
+	return &revision, nil
+}
+
+func (pr *PostsRepository) GetDeletedByID(ctx context.Context, id int) (*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT id, title, content, created_at, updated_at, tags, version, COALESCE(user_id, 0), deleted_at FROM posts
+					WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	var post models.Post
+
+	tags := pq.StringArray{}
+	err := pr.DB.QueryRowContext(ctx, query, id).Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &tags, &post.Version, &post.UserID, &post.DeletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPostDoesNotExist
+		}
+		return nil, err
+	}
+	post.Tags = tags
+	return &post, nil
+}
+
+func (pr *PostsRepository) GetDeletedByUser(ctx context.Context, userID int) ([]models.Post, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT id, title, content, created_at, updated_at, tags, version, COALESCE(user_id, 0), deleted_at FROM posts
+					WHERE user_id = $1 AND deleted_at IS NOT NULL
+					ORDER BY deleted_at DESC, id DESC`
+
+	rows, err := pr.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]models.Post, 0)
+
+	for rows.Next() {
+		var post models.Post
+		tags := pq.StringArray{}
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &tags, &post.Version, &post.UserID, &post.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		post.Tags = tags
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (pr *PostsRepository) Restore(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `UPDATE posts SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+	result, err := pr.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrPostDoesNotExist
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes posts which were moved to trash before deletedBefore.
+func (pr *PostsRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, cancel := withTimeout(ctx, pr.QueryTimeout)
+	defer cancel()
+
+	query := `DELETE FROM posts WHERE deleted_at < $1`
+	result, err := pr.DB.ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+func highlightSnippet(snippet *string) *string {
+	if snippet == nil {
+		return nil
+	}
+	highlighted := snippetHighlighter.Replace(html.EscapeString(*snippet))
+	return &highlighted
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"github.com/fdemchenko/arcus/internal/models"
+	"github.com/lib/pq"
+	"time"
+)
+
+type TagsRepository struct {
+	DB           DBTX
+	QueryTimeout time.Duration
+}
+
+func (tr *TagsRepository) GetAll(ctx context.Context) ([]models.Tag, error) {
+	ctx, cancel := withTimeout(ctx, tr.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT tag, count(*) FROM posts, unnest(tags) tag
+					WHERE deleted_at IS NULL
+					GROUP BY tag
+					ORDER BY count(*) DESC, tag ASC`
+
+	rows, err := tr.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]models.Tag, 0)
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.PostsCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Merge replaces every tag from sources with target across all posts,
+// removing duplicates that may appear. It returns amount of updated posts.
+func (tr *TagsRepository) Merge(ctx context.Context, sources []string, target string) (int, error) {
+	ctx, cancel := withTimeout(ctx, tr.QueryTimeout)
+	defer cancel()
+
+	query := `UPDATE posts SET
+					tags = (
+						SELECT array_agg(merged.tag ORDER BY merged.position)
+						FROM (
+							SELECT CASE WHEN tag = ANY($1) THEN $2 ELSE tag END AS tag, min(position) AS position
+							FROM unnest(tags) WITH ORDINALITY AS t(tag, position)
+							GROUP BY 1
+						) merged
+					),
+					version = version + 1
+					WHERE tags && $1`
+
+	result, err := tr.DB.ExecContext(ctx, query, pq.StringArray(sources), target)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
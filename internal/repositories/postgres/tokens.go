@@ -1,8 +1,10 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/fdemchenko/arcus/internal/models"
 )
@@ -10,22 +12,29 @@ import (
 var ErrTokenNotFound = errors.New("token not found")
 
 type TokensRepository struct {
-	DB *sql.DB
+	DB           DBTX
+	QueryTimeout time.Duration
 }
 
-func (tr *TokensRepository) Insert(token models.Token) error {
+func (tr *TokensRepository) Insert(ctx context.Context, token models.Token) error {
+	ctx, cancel := withTimeout(ctx, tr.QueryTimeout)
+	defer cancel()
+
 	query := `INSERT INTO tokens (scope, expires, token_hash, user_id)
 					VALUES ($1, $2, $3, $4)`
-	_, err := tr.DB.Exec(query, token.Scope, token.ExpiresAt, token.Hash, token.UserID)
+	_, err := tr.DB.ExecContext(ctx, query, token.Scope, token.ExpiresAt, token.Hash, token.UserID)
 	return err
 }
 
-func (tr *TokensRepository) GetByTokenHash(hash []byte, scope string) (*models.Token, error) {
+func (tr *TokensRepository) GetByTokenHash(ctx context.Context, hash []byte, scope string) (*models.Token, error) {
+	ctx, cancel := withTimeout(ctx, tr.QueryTimeout)
+	defer cancel()
+
 	query := `SELECT id, user_id, expires, token_hash, scope FROM tokens
 					WHERE scope = $1 AND token_hash = $2 AND expires > CURRENT_TIMESTAMP`
 
 	var token models.Token
-	err := tr.DB.QueryRow(query, scope, hash).
+	err := tr.DB.QueryRowContext(ctx, query, scope, hash).
 		Scan(
 			&token.ID,
 			&token.UserID,
@@ -42,8 +51,11 @@ func (tr *TokensRepository) GetByTokenHash(hash []byte, scope string) (*models.T
 	return &token, nil
 }
 
-func (tr *TokensRepository) DeleteAllForUser(userID int, scope string) error {
+func (tr *TokensRepository) DeleteAllForUser(ctx context.Context, userID int, scope string) error {
+	ctx, cancel := withTimeout(ctx, tr.QueryTimeout)
+	defer cancel()
+
 	query := `DELETE FROM tokens WHERE user_id = $1 AND scope = $2`
-	_, err := tr.DB.Exec(query, userID, scope)
+	_, err := tr.DB.ExecContext(ctx, query, userID, scope)
 	return err
 }
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so repositories
+// can run queries either directly or within a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// UnitOfWork runs several repository calls in a single transaction.
+// R is a set of repositories, created by newRepositories on top of the transaction.
+type UnitOfWork[R any] struct {
+	db              *sql.DB
+	newRepositories func(DBTX) R
+}
+
+func NewUnitOfWork[R any](db *sql.DB, newRepositories func(DBTX) R) *UnitOfWork[R] {
+	return &UnitOfWork[R]{db: db, newRepositories: newRepositories}
+}
+
+// Do commits transaction if fn succeeds, and rolls it back if fn returns error or panics.
+// Transaction is also rolled back if ctx is done before commit.
+func (uow *UnitOfWork[R]) Do(ctx context.Context, fn func(repositories R) error) error {
+	tx, err := uow.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(uow.newRepositories(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// withTimeout limits query execution time, so queries don't outlive both
+// the caller and the configured timeout. Zero timeout means no limit.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -1,8 +1,10 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/fdemchenko/arcus/internal/models"
 	"github.com/fdemchenko/arcus/internal/repositories"
@@ -12,13 +14,17 @@ import (
 var ErrUserDoesNotExists = errors.New("user does not exists")
 
 type UsersRepository struct {
-	DB *sql.DB
+	DB           DBTX
+	QueryTimeout time.Duration
 }
 
-func (ur *UsersRepository) Insert(user models.User) (int, error) {
-	query := `INSERT INTO users (name, email, password_hash)
-					VALUES ($1, $2, $3) RETURNING id`
-	row := ur.DB.QueryRow(query, user.Name, user.Email, user.Password.Hash)
+func (ur *UsersRepository) Insert(ctx context.Context, user models.User) (int, error) {
+	ctx, cancel := withTimeout(ctx, ur.QueryTimeout)
+	defer cancel()
+
+	query := `INSERT INTO users (name, email, password_hash, locale)
+					VALUES ($1, $2, $3, $4) RETURNING id`
+	row := ur.DB.QueryRowContext(ctx, query, user.Name, user.Email, user.Password.Hash, user.Locale)
 
 	var id int
 	err := row.Scan(&id)
@@ -27,18 +33,39 @@ func (ur *UsersRepository) Insert(user models.User) (int, error) {
 		if errors.As(err, &pgError) && pgError.Code == pq.ErrorCode(repositories.UniqueViolationErrorCode) {
 			return 0, repositories.ErrEmailAlreadyExists
 		}
-		return 0, nil
+		return 0, err
 	}
 
 	return id, nil
 }
 
-func (ur *UsersRepository) GetByID(userID int) (*models.User, error) {
-	query := `SELECT id, name, email, password_hash, activated, created_at, updated_at
+func (ur *UsersRepository) GetByID(ctx context.Context, userID int) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, ur.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT id, name, email, password_hash, activated, is_admin, locale, created_at, updated_at
 					 FROM users WHERE id = $1`
 	var user models.User
-	row := ur.DB.QueryRow(query, userID)
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password.Hash, &user.Activated, &user.CreatedAt, &user.UpdatedAt)
+	row := ur.DB.QueryRowContext(ctx, query, userID)
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password.Hash, &user.Activated, &user.IsAdmin, &user.Locale, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserDoesNotExists
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (ur *UsersRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, ur.QueryTimeout)
+	defer cancel()
+
+	query := `SELECT id, name, email, password_hash, activated, is_admin, locale, created_at, updated_at
+					 FROM users WHERE email = $1`
+	var user models.User
+	row := ur.DB.QueryRowContext(ctx, query, email)
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password.Hash, &user.Activated, &user.IsAdmin, &user.Locale, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserDoesNotExists
@@ -48,8 +75,32 @@ func (ur *UsersRepository) GetByID(userID int) (*models.User, error) {
 	return &user, nil
 }
 
-func (ur *UsersRepository) Activate(userID int) error {
+func (ur *UsersRepository) Activate(ctx context.Context, userID int) error {
+	ctx, cancel := withTimeout(ctx, ur.QueryTimeout)
+	defer cancel()
+
 	query := `UPDATE users SET activated = TRUE WHERE id = $1`
-	_, err := ur.DB.Exec(query, userID)
+	_, err := ur.DB.ExecContext(ctx, query, userID)
 	return err
 }
+
+func (ur *UsersRepository) UpdatePassword(ctx context.Context, userID int, passwordHash []byte) error {
+	ctx, cancel := withTimeout(ctx, ur.QueryTimeout)
+	defer cancel()
+
+	query := `UPDATE users SET password_hash = $1 WHERE id = $2`
+	result, err := ur.DB.ExecContext(ctx, query, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserDoesNotExists
+	}
+	return nil
+}
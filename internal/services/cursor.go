@@ -0,0 +1,63 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fdemchenko/arcus/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type cursorPayload struct {
+	CreatedAt int64 `json:"t"`
+	ID        int   `json:"id"`
+}
+
+// encodeCursor returns opaque cursor in form of base64(payload).base64(HMAC-SHA256(payload)),
+// so clients are not able to craft cursors on their own.
+func encodeCursor(cursor models.PostsCursor, key []byte) (string, error) {
+	payload, err := json.Marshal(cursorPayload{CreatedAt: cursor.CreatedAt.UnixMicro(), ID: cursor.ID})
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	signature := base64.RawURLEncoding.EncodeToString(signCursor(encodedPayload, key))
+	return encodedPayload + "." + signature, nil
+}
+
+func decodeCursor(cursor string, key []byte) (*models.PostsCursor, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(cursor, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signCursor(encodedPayload, key)) {
+		return nil, ErrInvalidCursor
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &models.PostsCursor{CreatedAt: time.UnixMicro(payload.CreatedAt).UTC(), ID: payload.ID}, nil
+}
+
+func signCursor(encodedPayload string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/fdemchenko/arcus/internal/services/mail"
+)
+
+type EmailRenderer interface {
+	Render(templateName string, locale string, data interface{}) (*mail.Email, error)
+	Templates() []mail.TemplateInfo
+}
+
+// EmailTemplatesService lets admins inspect email templates without sending real emails.
+type EmailTemplatesService struct {
+	logger         *slog.Logger
+	renderer       EmailRenderer
+	registry       *mail.TemplateRegistry
+	mailerProducer MailerProducer
+}
+
+func NewEmailTemplatesService(
+	logger *slog.Logger,
+	renderer EmailRenderer,
+	registry *mail.TemplateRegistry,
+	mailerProducer MailerProducer,
+) *EmailTemplatesService {
+	return &EmailTemplatesService{
+		logger:         logger,
+		renderer:       renderer,
+		registry:       registry,
+		mailerProducer: mailerProducer,
+	}
+}
+
+func (ets *EmailTemplatesService) GetAll() []mail.TemplateInfo {
+	return ets.renderer.Templates()
+}
+
+// Preview renders template with the same data decoding and rendering as the mail consumer does.
+func (ets *EmailTemplatesService) Preview(templateName string, locale string, data json.RawMessage) (*mail.Email, error) {
+	templateData, err := ets.registry.Decode(templateName, data)
+	if err != nil {
+		return nil, err
+	}
+	return ets.renderer.Render(templateName, locale, templateData)
+}
+
+// TestSend queues template email to the given address.
+func (ets *EmailTemplatesService) TestSend(ctx context.Context, templateName string, to string, locale string, data json.RawMessage) error {
+	const op = "services.EmailTemplatesService.TestSend"
+	logger := ets.logger.With(slog.String("op", op))
+
+	// fail early instead of letting invalid command end up in dead letter queue
+	_, err := ets.Preview(templateName, locale, data)
+	if err != nil {
+		return err
+	}
+
+	err = ets.mailerProducer.Publish(ctx, mail.SendEmailCommand[any]{
+		To:           to,
+		TemplateName: templateName,
+		Locale:       locale,
+		TemplateData: data,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("test email queued", slog.String("template", templateName), slog.String("to", to))
+	return nil
+}
@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type DeadLetter struct {
+	Attempt   int       `json:"attempt"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+	Body      string    `json:"body"`
+}
+
+// DeadLetterQueue gives access to deliveries which exhausted their retries.
+// It must own its channel, since peeking nacks all deliveries received on it,
+// and puts the channel in confirm mode.
+type DeadLetterQueue struct {
+	channel *amqp.Channel
+}
+
+func NewDeadLetterQueue(channel *amqp.Channel) (*DeadLetterQueue, error) {
+	_, err := channel.QueueDeclare(
+		DeadLetterQueueName,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = channel.Confirm(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetterQueue{channel: channel}, nil
+}
+
+// Peek returns up to limit dead letters and leaves them in the queue.
+func (dlq *DeadLetterQueue) Peek(limit int) ([]DeadLetter, error) {
+	deadLetters := make([]DeadLetter, 0, limit)
+	var lastTag uint64
+
+	for len(deadLetters) < limit {
+		delivery, ok, err := dlq.channel.Get(DeadLetterQueueName, false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		lastTag = delivery.DeliveryTag
+		deadLetters = append(deadLetters, toDeadLetter(delivery))
+	}
+
+	if lastTag != 0 {
+		err := dlq.channel.Nack(lastTag, true, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return deadLetters, nil
+}
+
+// Replay moves up to limit dead letters back to the email queue with reset attempts counter.
+func (dlq *DeadLetterQueue) Replay(ctx context.Context, limit int) (int, error) {
+	replayed := 0
+	for replayed < limit {
+		delivery, ok, err := dlq.channel.Get(DeadLetterQueueName, false)
+		if err != nil {
+			return replayed, err
+		}
+		if !ok {
+			break
+		}
+
+		publishCtx, cancel := context.WithTimeout(ctx, republishTimeout)
+		err = publishConfirmed(publishCtx, dlq.channel, EmailQueueName, amqp.Publishing{
+			ContentType:  delivery.ContentType,
+			DeliveryMode: amqp.Persistent,
+			Body:         delivery.Body,
+		})
+		cancel()
+		if err != nil {
+			_ = delivery.Nack(false, true)
+			return replayed, err
+		}
+
+		err = delivery.Ack(false)
+		if err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func toDeadLetter(delivery amqp.Delivery) DeadLetter {
+	lastError, _ := delivery.Headers[LastErrorHeader].(string)
+	return DeadLetter{
+		Attempt:   deliveryAttempt(delivery),
+		LastError: lastError,
+		FailedAt:  delivery.Timestamp,
+		Body:      string(delivery.Body),
+	}
+}
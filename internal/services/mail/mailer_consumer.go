@@ -2,26 +2,54 @@ package mail
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 
+	"github.com/fdemchenko/arcus/internal/validator"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const EmailQueueName = "welcome_emails"
+const consumerTag = "arcus-mailer"
 
 type Sender interface {
-	Send(to string, templateName string, data interface{}) error
+	Send(to string, templateName string, locale string, data interface{}) error
+}
+
+type ConsumerOptions struct {
+	Workers  int
+	Prefetch int
+	// RateLimit is the maximum number of emails sent per second, zero disables limiting
+	RateLimit float64
+	RateBurst int
+	Retry     RetryPolicy
 }
 
 type MailerConsumer struct {
-	sender  Sender
-	channel *amqp.Channel
-	logger  *slog.Logger
+	sender   Sender
+	channel  *amqp.Channel
+	logger   *slog.Logger
+	registry *TemplateRegistry
+	options  ConsumerOptions
+	limiter  *tokenBucket
+	// done is closed once all workers exit
+	done chan struct{}
+	// stopCtx is cancelled by Shutdown to interrupt waiting for the rate limiter
+	stopCtx context.Context
+	stop    context.CancelFunc
 }
 
-func NewMailerConsumer(sender Sender, channel *amqp.Channel, logger *slog.Logger) (*MailerConsumer, error) {
+func NewMailerConsumer(
+	sender Sender,
+	channel *amqp.Channel,
+	logger *slog.Logger,
+	registry *TemplateRegistry,
+	options ConsumerOptions,
+) (*MailerConsumer, error) {
 	_, err := channel.QueueDeclare(
 		EmailQueueName,
 		true,
@@ -35,19 +63,49 @@ func NewMailerConsumer(sender Sender, channel *amqp.Channel, logger *slog.Logger
 		return nil, err
 	}
 
+	err = declareRetryTopology(channel, options.Retry)
+	if err != nil {
+		return nil, err
+	}
+
+	// retries are published on the same channel and must be confirmed before acking
+	err = channel.Confirm(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var limiter *tokenBucket
+	if options.RateLimit > 0 {
+		limiter = newTokenBucket(options.RateLimit, options.RateBurst)
+	}
+
+	stopCtx, stop := context.WithCancel(context.Background())
 	return &MailerConsumer{
-		sender:  sender,
-		channel: channel,
-		logger:  logger,
+		sender:   sender,
+		channel:  channel,
+		logger:   logger,
+		registry: registry,
+		options:  options,
+		limiter:  limiter,
+		done:     make(chan struct{}),
+		stopCtx:  stopCtx,
+		stop:     stop,
 	}, nil
 }
 
 func (mc *MailerConsumer) StartConsuming() error {
 	const op = "mail.MailerConsumer.StartConsuming"
 	logger := mc.logger.With(slog.String("op", op))
+
+	// without prefetch limit broker pushes the whole queue to the consumer
+	err := mc.channel.Qos(mc.options.Prefetch, 0, false)
+	if err != nil {
+		return err
+	}
+
 	deliveriesChan, err := mc.channel.Consume(
 		EmailQueueName,
-		"",
+		consumerTag,
 		false,
 		false,
 		false,
@@ -58,29 +116,103 @@ func (mc *MailerConsumer) StartConsuming() error {
 		return err
 	}
 
-	go func() {
-		for delivery := range deliveriesChan {
-			err = mc.handleDelivery(delivery)
-			if err != nil {
-				logger.Error("failed to handle amqp delivery", slog.String("error", err.Error()))
-			}
-			err := delivery.Ack(false)
-			if err != nil {
-				logger.Error("failed to ack delivery", slog.String("error", err.Error()))
+	var workers sync.WaitGroup
+	for range max(mc.options.Workers, 1) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for delivery := range deliveriesChan {
+				mc.processDelivery(logger, delivery)
 			}
-		}
+		}()
+	}
+
+	go func() {
+		workers.Wait()
+		close(mc.done)
 	}()
 	return nil
 }
 
-func (mc *MailerConsumer) handleDelivery(delivery amqp.Delivery) error {
-	var sendEmailCommand SendEmailCommand[UserWelcomeData]
+func (mc *MailerConsumer) processDelivery(logger *slog.Logger, delivery amqp.Delivery) {
+	// deliveries prefetched before cancellation are returned to the queue
+	if mc.stopCtx.Err() != nil {
+		mc.requeue(logger, delivery)
+		return
+	}
+
+	err := mc.handleDelivery(mc.stopCtx, delivery)
+	if errors.Is(err, context.Canceled) {
+		// shutdown interrupted waiting for the rate limiter, the email was not sent
+		mc.requeue(logger, delivery)
+		return
+	}
+	if err != nil {
+		logger.Error("failed to handle amqp delivery", slog.String("error", err.Error()))
+
+		deadLettered, err := mc.scheduleRetry(delivery, err)
+		if err != nil {
+			// keep the message in the queue rather than lose it
+			logger.Error("failed to schedule delivery retry", slog.String("error", err.Error()))
+			mc.requeue(logger, delivery)
+			return
+		}
+		if deadLettered {
+			logger.Warn("delivery moved to dead letter queue", slog.Int("attempt", deliveryAttempt(delivery)))
+		}
+	}
+	err = delivery.Ack(false)
+	if err != nil {
+		logger.Error("failed to ack delivery", slog.String("error", err.Error()))
+	}
+}
+
+func (mc *MailerConsumer) requeue(logger *slog.Logger, delivery amqp.Delivery) {
+	if err := delivery.Nack(false, true); err != nil {
+		logger.Error("failed to nack delivery", slog.String("error", err.Error()))
+	}
+}
+
+// Shutdown stops accepting new deliveries and waits until the ones being processed
+// are finished or ctx is done. Deliveries waiting for the rate limiter are requeued.
+func (mc *MailerConsumer) Shutdown(ctx context.Context) error {
+	mc.stop()
+	if err := mc.channel.Cancel(consumerTag, false); err != nil {
+		return err
+	}
+
+	select {
+	case <-mc.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (mc *MailerConsumer) handleDelivery(ctx context.Context, delivery amqp.Delivery) error {
+	var sendEmailCommand SendEmailCommand[json.RawMessage]
 	err := json.NewDecoder(bytes.NewReader(delivery.Body)).Decode(&sendEmailCommand)
 	if err != nil {
-		return fmt.Errorf("cannot decode json SendEmailCommand: %w", err)
+		return fmt.Errorf("%w: %w", ErrMalformedCommand, err)
+	}
+	if !validator.IsValidEmail(sendEmailCommand.To) {
+		return fmt.Errorf("%w: invalid recipient address %q", ErrMalformedCommand, sendEmailCommand.To)
+	}
+
+	// unknown templates and invalid data cannot be fixed by retrying
+	templateData, err := mc.registry.Decode(sendEmailCommand.TemplateName, sendEmailCommand.TemplateData)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedCommand, err)
+	}
+
+	if mc.limiter != nil {
+		err = mc.limiter.Wait(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = mc.sender.Send(sendEmailCommand.To, sendEmailCommand.TemplateName, sendEmailCommand.TemplateData)
+	err = mc.sender.Send(sendEmailCommand.To, sendEmailCommand.TemplateName, sendEmailCommand.Locale, templateData)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
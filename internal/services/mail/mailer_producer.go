@@ -1,17 +1,57 @@
 package mail
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+var ErrPublishNotConfirmed = errors.New("broker did not confirm published message")
+
+// MailerProducer publishes commands over its own connection with publisher confirms,
+// so Publish returns only once the broker has taken responsibility for the message.
+// Closed channel or connection is re-opened on the next Publish.
 type MailerProducer struct {
+	url     string
+	mu      sync.Mutex
+	conn    *amqp.Connection
 	channel *amqp.Channel
 }
 
-func NewMailerProducer(channel *amqp.Channel) (*MailerProducer, error) {
-	_, err := channel.QueueDeclare(
+func NewMailerProducer(url string) (*MailerProducer, error) {
+	mp := &MailerProducer{url: url}
+	if err := mp.connect(); err != nil {
+		return nil, err
+	}
+	return mp, nil
+}
+
+// connect opens connection if needed and a new channel in confirm mode. Caller must hold mu.
+func (mp *MailerProducer) connect() error {
+	if mp.conn == nil || mp.conn.IsClosed() {
+		conn, err := amqp.Dial(mp.url)
+		if err != nil {
+			return err
+		}
+		mp.conn = conn
+	}
+
+	channel, err := mp.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	err = channel.Confirm(false)
+	if err != nil {
+		channel.Close()
+		return err
+	}
+
+	_, err = channel.QueueDeclare(
 		EmailQueueName,
 		true,
 		false,
@@ -19,30 +59,74 @@ func NewMailerProducer(channel *amqp.Channel) (*MailerProducer, error) {
 		false,
 		nil,
 	)
-
 	if err != nil {
-		return nil, err
+		channel.Close()
+		return err
 	}
 
-	return &MailerProducer{
-		channel: channel,
-	}, nil
+	mp.channel = channel
+	return nil
 }
 
-func (mp *MailerProducer) Publish(command SendEmailCommand[any]) error {
+func (mp *MailerProducer) Publish(ctx context.Context, command SendEmailCommand[any]) error {
 	js, err := json.Marshal(command)
 	if err != nil {
 		return err
 	}
-	return mp.channel.Publish(
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.channel == nil || mp.channel.IsClosed() {
+		if err := mp.connect(); err != nil {
+			return fmt.Errorf("failed to reopen AMQP channel: %w", err)
+		}
+	}
+
+	return publishConfirmed(ctx, mp.channel, EmailQueueName, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         js,
+	})
+}
+
+// publishConfirmed publishes to the default exchange and waits for the broker
+// confirmation. Channel must be in confirm mode.
+func publishConfirmed(ctx context.Context, channel *amqp.Channel, routingKey string, publishing amqp.Publishing) error {
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(
+		ctx,
 		"",
-		EmailQueueName,
+		routingKey,
 		false,
 		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp.Persistent,
-			Body:         js,
-		},
+		publishing,
 	)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrPublishNotConfirmed
+	}
+	return nil
+}
+
+// Close closes producer channel and connection.
+func (mp *MailerProducer) Close() error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.channel != nil && !mp.channel.IsClosed() {
+		if err := mp.channel.Close(); err != nil {
+			return err
+		}
+	}
+	if mp.conn != nil && !mp.conn.IsClosed() {
+		return mp.conn.Close()
+	}
+	return nil
 }
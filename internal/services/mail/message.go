@@ -1,12 +1,38 @@
 package mail
 
+import "github.com/fdemchenko/arcus/internal/validator"
+
+const (
+	UserWelcomeTemplate   = "user_welcome.tmpl"
+	PasswordResetTemplate = "password_reset.tmpl"
+)
+
 type SendEmailCommand[T any] struct {
 	To           string `json:"to"`
 	TemplateName string `json:"template_name"`
+	// Locale selects localized template version, default one is used when empty or not found
+	Locale       string `json:"locale,omitempty"`
 	TemplateData T      `json:"template_data"`
 }
 
 type UserWelcomeData struct {
 	Token string `json:"token"`
 	Name  string `json:"name"`
+	// ActivationLink activates account in one click, older messages may not have it
+	ActivationLink string `json:"activation_link,omitempty"`
+}
+
+type PasswordResetData struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+func (d *UserWelcomeData) Validate(v validator.Validator) {
+	v.Check(d.Token != "", "token", "must be provided")
+	v.Check(d.Name != "", "name", "must be provided")
+}
+
+func (d *PasswordResetData) Validate(v validator.Validator) {
+	v.Check(d.Token != "", "token", "must be provided")
+	v.Check(d.Name != "", "name", "must be provided")
 }
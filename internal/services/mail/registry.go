@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/fdemchenko/arcus/internal/validator"
+)
+
+var (
+	ErrUnknownTemplate     = errors.New("unknown email template")
+	ErrInvalidTemplateData = errors.New("invalid email template data")
+)
+
+// TemplateData is a payload of an email template.
+type TemplateData interface {
+	Validate(v validator.Validator)
+}
+
+// TemplateRegistry maps template names to their payload types, so that
+// template data can be decoded and validated before rendering.
+type TemplateRegistry struct {
+	factories map[string]func() TemplateData
+}
+
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{factories: make(map[string]func() TemplateData)}
+}
+
+// DefaultTemplateRegistry contains all templates sent by the application.
+func DefaultTemplateRegistry() *TemplateRegistry {
+	registry := NewTemplateRegistry()
+	registry.Register(UserWelcomeTemplate, func() TemplateData { return &UserWelcomeData{} })
+	registry.Register(PasswordResetTemplate, func() TemplateData { return &PasswordResetData{} })
+	return registry
+}
+
+// Register associates template with a factory of an empty payload pointer.
+func (tr *TemplateRegistry) Register(templateName string, factory func() TemplateData) {
+	tr.factories[templateName] = factory
+}
+
+func (tr *TemplateRegistry) Templates() []string {
+	names := make([]string, 0, len(tr.factories))
+	for name := range tr.factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Decode decodes raw template data into payload type registered for templateName and validates it.
+func (tr *TemplateRegistry) Decode(templateName string, raw json.RawMessage) (TemplateData, error) {
+	factory, exists := tr.factories[templateName]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, templateName)
+	}
+
+	data := factory()
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidTemplateData, templateName, err)
+	}
+
+	v := validator.New()
+	data.Validate(v)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTemplateData, templateName, v.Errors)
+	}
+	return data, nil
+}
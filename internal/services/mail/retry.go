@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	DeadLetterQueueName = EmailQueueName + ".dlq"
+
+	AttemptHeader   = "x-arcus-attempt"
+	LastErrorHeader = "x-arcus-last-error"
+
+	republishTimeout = 5 * time.Second
+)
+
+// ErrMalformedCommand marks deliveries which will never succeed, so they are dead lettered without retries.
+var ErrMalformedCommand = errors.New("malformed send email command")
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Delay returns backoff before the given attempt, where the first retry is attempt 2.
+func (rp RetryPolicy) Delay(attempt int) time.Duration {
+	delay := rp.BaseDelay
+	for i := 2; i < attempt && delay < rp.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, rp.MaxDelay)
+}
+
+func retryQueueName(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", EmailQueueName, delay)
+}
+
+// declareRetryTopology declares one retry queue per distinct backoff delay. Messages wait in
+// a retry queue until TTL expires and then are dead lettered back to the main queue.
+func declareRetryTopology(channel *amqp.Channel, policy RetryPolicy) error {
+	for attempt := 2; attempt <= policy.MaxAttempts; attempt++ {
+		delay := policy.Delay(attempt)
+		_, err := channel.QueueDeclare(
+			retryQueueName(delay),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": EmailQueueName,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := channel.QueueDeclare(
+		DeadLetterQueueName,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	return err
+}
+
+// deliveryAttempt returns attempt number of the delivery, starting from 1.
+func deliveryAttempt(delivery amqp.Delivery) int {
+	switch v := delivery.Headers[AttemptHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 1
+	}
+}
+
+// scheduleRetry re-publishes failed delivery to the matching retry queue or,
+// once attempts are exhausted, to the dead letter queue.
+func (mc *MailerConsumer) scheduleRetry(delivery amqp.Delivery, handleErr error) (deadLettered bool, err error) {
+	attempt := deliveryAttempt(delivery)
+	// dead lettered messages keep the number of attempts made
+	routingKey, nextAttempt := DeadLetterQueueName, attempt
+	if attempt < mc.options.Retry.MaxAttempts && !errors.Is(handleErr, ErrMalformedCommand) {
+		routingKey, nextAttempt = retryQueueName(mc.options.Retry.Delay(attempt+1)), attempt+1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), republishTimeout)
+	defer cancel()
+
+	// delivery is acked afterwards, so the copy must be confirmed first
+	err = publishConfirmed(ctx, mc.channel, routingKey, amqp.Publishing{
+		ContentType:  delivery.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Headers: amqp.Table{
+			AttemptHeader:   int32(nextAttempt),
+			LastErrorHeader: handleErr.Error(),
+		},
+		Body: delivery.Body,
+	})
+	return routingKey == DeadLetterQueueName, err
+}
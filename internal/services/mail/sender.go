@@ -3,68 +3,106 @@ package mail
 import (
 	"bytes"
 	"fmt"
-	"html/template"
 	"io/fs"
-
-	"github.com/fdemchenko/arcus/internal/config"
-	"gopkg.in/gomail.v2"
+	"strings"
+	"time"
 )
 
 type MailSender struct {
-	dialer         *gomail.Dialer
-	sender         string
-	templatesFS    fs.FS
-	templatesCache map[string]*template.Template
+	transport Transport
+	sender    string
+	templates *templateCache
 }
 
-func NewMailSender(cfg config.SMTPConfig, templatesFS fs.FS) *MailSender {
-	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
-	cache := make(map[string]*template.Template)
+// NewMailSender parses all templates upfront, so that broken ones are reported at startup.
+func NewMailSender(senderAddress string, transport Transport, templatesFS fs.FS) (*MailSender, error) {
+	templates, err := parseTemplates(templatesFS)
+	if err != nil {
+		return nil, err
+	}
 
-	return &MailSender{dialer: dialer, sender: cfg.SenderAddress, templatesFS: templatesFS, templatesCache: cache}
+	return &MailSender{transport: transport, sender: senderAddress, templates: &templateCache{templates: templates}}, nil
 }
 
-func (m *MailSender) prepareTemplate(templateName string) (*template.Template, error) {
-	if cachedTemplate, exists := m.templatesCache[templateName]; exists {
-		return cachedTemplate, nil
+func (m *MailSender) prepareTemplate(templateName string) (*emailTemplate, error) {
+	t, exists := m.templates.get(templateName)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, templateName)
 	}
-	parsedTemplate, err := template.ParseFS(m.templatesFS, templateName)
-	if err != nil {
-		return nil, fmt.Errorf("failer to parse email templates: %w", err)
+	return t, nil
+}
+
+// resolveTemplateName returns the most specific existing template for locale, checking
+// <name>.<locale>.tmpl, then <name>.<language>.tmpl and finally the default template.
+func (m *MailSender) resolveTemplateName(templateName string, locale string) string {
+	if locale == "" {
+		return templateName
 	}
-	return parsedTemplate, nil
+
+	baseName := strings.TrimSuffix(templateName, templateExtension)
+	candidates := []string{baseName + "." + locale + templateExtension}
+	if language, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, baseName+"."+language+templateExtension)
+	}
+
+	for _, candidate := range candidates {
+		if _, exists := m.templates.get(candidate); exists {
+			return candidate
+		}
+	}
+	return templateName
 }
 
-func (m *MailSender) Send(to string, templateName string, data interface{}) error {
-	t, err := m.prepareTemplate(templateName)
+// Render executes subject and bodies of the template resolved for locale.
+func (m *MailSender) Render(templateName string, locale string, data interface{}) (*Email, error) {
+	t, err := m.prepareTemplate(m.resolveTemplateName(templateName, strings.ToLower(locale)))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	subject := new(bytes.Buffer)
-	err = t.ExecuteTemplate(subject, "subject", nil)
+	err = t.text.ExecuteTemplate(subject, "subject", data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	textBody := new(bytes.Buffer)
-	err = t.ExecuteTemplate(textBody, "plainBody", data)
+	err = t.text.ExecuteTemplate(textBody, "plainBody", data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	htmlBody := new(bytes.Buffer)
-	err = t.ExecuteTemplate(htmlBody, "htmlBody", data)
+	err = t.html.ExecuteTemplate(htmlBody, "htmlBody", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		From:      m.sender,
+		Subject:   subject.String(),
+		PlainBody: textBody.String(),
+		HTMLBody:  htmlBody.String(),
+	}, nil
+}
+
+func (m *MailSender) Send(to string, templateName string, locale string, data interface{}) error {
+	email, err := m.Render(templateName, locale, data)
 	if err != nil {
 		return err
 	}
 
-	message := gomail.NewMessage()
-	message.SetHeader("From", m.sender)
-	message.SetHeader("To", to)
-	message.SetHeader("Subject", subject.String())
-	message.SetBody("text/plain", textBody.String())
-	message.AddAlternative("text/html", htmlBody.String())
+	email.To = to
+	email.SentAt = time.Now()
+	return m.transport.Send(*email)
+}
+
+// Templates returns loaded default templates along with locales they are translated to.
+func (m *MailSender) Templates() []TemplateInfo {
+	return m.templates.list()
+}
 
-	return m.dialer.DialAndSend(message)
+// Close releases resources held by the transport.
+func (m *MailSender) Close() error {
+	return m.transport.Close()
 }
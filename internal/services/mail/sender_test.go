@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fdemchenko/arcus/templates"
+)
+
+func TestMailSenderRenderEscapesOnlyHTMLBody(t *testing.T) {
+	sender, err := NewMailSender("arcus@example.com", NewMemoryTransport(1), templates.TemplatesFS)
+	if err != nil {
+		t.Fatalf("failed to create mail sender: %v", err)
+	}
+
+	const name = "O'Brien & Co"
+	tests := []struct {
+		templateName string
+		locale       string
+		data         TemplateData
+	}{
+		{UserWelcomeTemplate, "", &UserWelcomeData{Token: "token", Name: name}},
+		{UserWelcomeTemplate, "uk", &UserWelcomeData{Token: "token", Name: name}},
+		{PasswordResetTemplate, "", &PasswordResetData{Token: "token", Name: name}},
+		{PasswordResetTemplate, "uk", &PasswordResetData{Token: "token", Name: name}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.templateName+"/"+tt.locale, func(t *testing.T) {
+			email, err := sender.Render(tt.templateName, tt.locale, tt.data)
+			if err != nil {
+				t.Fatalf("failed to render: %v", err)
+			}
+
+			if !strings.Contains(email.Subject, name) {
+				t.Errorf("subject %q does not contain unescaped name %q", email.Subject, name)
+			}
+			if !strings.Contains(email.PlainBody, name) {
+				t.Errorf("plain body %q does not contain unescaped name %q", email.PlainBody, name)
+			}
+			if !strings.Contains(email.HTMLBody, "O&#39;Brien &amp; Co") {
+				t.Errorf("html body %q does not contain escaped name", email.HTMLBody)
+			}
+		})
+	}
+}
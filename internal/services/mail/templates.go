@@ -0,0 +1,164 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+const templateExtension = ".tmpl"
+
+// requiredTemplateBlocks must be defined by every email template.
+var requiredTemplateBlocks = []string{"subject", "plainBody", "htmlBody"}
+
+type TemplateInfo struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+// emailTemplate keeps a template file parsed twice: subject and plain body must not be
+// HTML-escaped, so only the HTML body is rendered with html/template.
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templateCache holds parsed templates which can be swapped at runtime, e.g. on reload.
+type templateCache struct {
+	mu        sync.RWMutex
+	templates map[string]*emailTemplate
+}
+
+func (tc *templateCache) get(templateName string) (*emailTemplate, bool) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	t, exists := tc.templates[templateName]
+	return t, exists
+}
+
+func (tc *templateCache) replace(templates map[string]*emailTemplate) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.templates = templates
+}
+
+func (tc *templateCache) list() []TemplateInfo {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
+	locales := make(map[string][]string)
+	for name := range tc.templates {
+		// localized templates are named <name>.<locale>.tmpl
+		baseName, locale, localized := strings.Cut(strings.TrimSuffix(name, templateExtension), ".")
+		baseName += templateExtension
+		if _, exists := locales[baseName]; !exists {
+			locales[baseName] = []string{}
+		}
+		if localized {
+			locales[baseName] = append(locales[baseName], locale)
+		}
+	}
+
+	infos := make([]TemplateInfo, 0, len(locales))
+	for name, templateLocales := range locales {
+		slices.Sort(templateLocales)
+		infos = append(infos, TemplateInfo{Name: name, Locales: templateLocales})
+	}
+	slices.SortFunc(infos, func(a, b TemplateInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos
+}
+
+// parseTemplates parses every template in templatesFS and checks that it defines all required blocks.
+func parseTemplates(templatesFS fs.FS) (map[string]*emailTemplate, error) {
+	names, err := fs.Glob(templatesFS, "*"+templateExtension)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*emailTemplate, len(names))
+	for _, name := range names {
+		textTemplate, err := texttemplate.ParseFS(templatesFS, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+		htmlTemplate, err := htmltemplate.ParseFS(templatesFS, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+
+		for _, block := range requiredTemplateBlocks {
+			if textTemplate.Lookup(block) == nil {
+				return nil, fmt.Errorf("email template %s does not define %q block", name, block)
+			}
+		}
+		templates[name] = &emailTemplate{text: textTemplate, html: htmlTemplate}
+	}
+	return templates, nil
+}
+
+// templatesFingerprint changes whenever any template is added, removed or modified.
+func templatesFingerprint(templatesFS fs.FS) (string, error) {
+	names, err := fs.Glob(templatesFS, "*"+templateExtension)
+	if err != nil {
+		return "", err
+	}
+
+	var fingerprint strings.Builder
+	for _, name := range names {
+		info, err := fs.Stat(templatesFS, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String(), nil
+}
+
+// WatchTemplates re-parses templates from templatesFS every time they change until ctx is done.
+// Invalid templates are reported and previous versions are kept. Intended for development only.
+func (m *MailSender) WatchTemplates(ctx context.Context, templatesFS fs.FS, interval time.Duration, logger *slog.Logger) {
+	const op = "mail.MailSender.WatchTemplates"
+	logger = logger.With(slog.String("op", op))
+
+	lastFingerprint, err := templatesFingerprint(templatesFS)
+	if err != nil {
+		logger.Error("failed to stat email templates", slog.String("error", err.Error()))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fingerprint, err := templatesFingerprint(templatesFS)
+			if err != nil {
+				logger.Error("failed to stat email templates", slog.String("error", err.Error()))
+				continue
+			}
+			if fingerprint == lastFingerprint {
+				continue
+			}
+			lastFingerprint = fingerprint
+
+			templates, err := parseTemplates(templatesFS)
+			if err != nil {
+				logger.Error("failed to reload email templates", slog.String("error", err.Error()))
+				continue
+			}
+			m.templates.replace(templates)
+			logger.Info("email templates reloaded", slog.Int("amount", len(templates)))
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package mail
+
+import "time"
+
+// Email is a rendered message ready to be delivered by a Transport.
+type Email struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	PlainBody string    `json:"plain_body"`
+	HTMLBody  string    `json:"html_body"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// Transport delivers rendered emails.
+type Transport interface {
+	Send(email Email) error
+	Close() error
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// FileTransport writes every email as an .eml file into a directory.
+type FileTransport struct {
+	directory string
+	counter   atomic.Uint64
+}
+
+func NewFileTransport(directory string) (*FileTransport, error) {
+	err := os.MkdirAll(directory, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &FileTransport{directory: directory}, nil
+}
+
+func (t *FileTransport) Send(email Email) error {
+	// counter keeps names unique for emails sent within the same nanosecond
+	name := fmt.Sprintf("%d-%d.eml", email.SentAt.UnixNano(), t.counter.Add(1))
+	file, err := os.Create(filepath.Join(t.directory, name))
+	if err != nil {
+		return err
+	}
+
+	_, err = toGomailMessage(email).WriteTo(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (t *FileTransport) Close() error {
+	return nil
+}
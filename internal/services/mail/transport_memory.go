@@ -0,0 +1,49 @@
+package mail
+
+import "sync"
+
+// MemoryTransport captures sent emails in memory, keeping up to capacity most recent ones.
+type MemoryTransport struct {
+	mu       sync.RWMutex
+	emails   []Email
+	capacity int
+}
+
+func NewMemoryTransport(capacity int) *MemoryTransport {
+	return &MemoryTransport{capacity: max(capacity, 1)}
+}
+
+func (t *MemoryTransport) Send(email Email) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.emails = append(t.emails, email)
+	if len(t.emails) > t.capacity {
+		t.emails = t.emails[len(t.emails)-t.capacity:]
+	}
+	return nil
+}
+
+// Emails returns captured emails, optionally filtered by recipient, newest first.
+func (t *MemoryTransport) Emails(to string) []Email {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	emails := make([]Email, 0, len(t.emails))
+	for i := len(t.emails) - 1; i >= 0; i-- {
+		if to == "" || t.emails[i].To == to {
+			emails = append(emails, t.emails[i])
+		}
+	}
+	return emails
+}
+
+func (t *MemoryTransport) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.emails = nil
+}
+
+func (t *MemoryTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	netmail "net/mail"
+	"syscall"
+
+	"github.com/fdemchenko/arcus/internal/config"
+	"gopkg.in/gomail.v2"
+)
+
+// SMTPTransport reuses SMTP connections between messages instead of dialing for each one.
+type SMTPTransport struct {
+	dialer *gomail.Dialer
+	idle   chan gomail.SendCloser
+}
+
+func NewSMTPTransport(cfg config.SMTPConfig) *SMTPTransport {
+	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	return &SMTPTransport{dialer: dialer, idle: make(chan gomail.SendCloser, max(cfg.MaxIdleConnections, 1))}
+}
+
+func (t *SMTPTransport) Send(email Email) error {
+	from, err := netmail.ParseAddress(email.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := netmail.ParseAddress(email.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+	message := &trackedMessage{Message: toGomailMessage(email)}
+
+	conn, pooled, err := t.get()
+	if err != nil {
+		return err
+	}
+
+	err = conn.Send(from.Address, []string{to.Address}, message)
+	// idle connection could have been closed by the server, it is safe to retry over
+	// a new one only if nothing has been submitted yet
+	if err != nil && pooled && !message.submitted && isBrokenConnection(err) {
+		conn.Close()
+		conn, err = t.dialer.Dial()
+		if err != nil {
+			return err
+		}
+		err = conn.Send(from.Address, []string{to.Address}, message)
+	}
+	if err != nil {
+		// connection state is unknown after a failure, so it is not reused
+		conn.Close()
+		return err
+	}
+
+	t.put(conn)
+	return nil
+}
+
+// get returns idle connection from the pool or dials a new one, reporting whether it was pooled.
+func (t *SMTPTransport) get() (gomail.SendCloser, bool, error) {
+	select {
+	case conn := <-t.idle:
+		return conn, true, nil
+	default:
+		conn, err := t.dialer.Dial()
+		return conn, false, err
+	}
+}
+
+func (t *SMTPTransport) put(conn gomail.SendCloser) {
+	select {
+	case t.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// Close closes all idle connections.
+func (t *SMTPTransport) Close() error {
+	for {
+		select {
+		case conn := <-t.idle:
+			if err := conn.Close(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// trackedMessage records whether message body has started being written, i.e. the server
+// accepted MAIL, RCPT and DATA commands.
+type trackedMessage struct {
+	*gomail.Message
+	submitted bool
+}
+
+func (m *trackedMessage) WriteTo(w io.Writer) (int64, error) {
+	m.submitted = true
+	return m.Message.WriteTo(w)
+}
+
+func isBrokenConnection(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+func toGomailMessage(email Email) *gomail.Message {
+	message := gomail.NewMessage()
+	message.SetHeader("From", email.From)
+	message.SetHeader("To", email.To)
+	message.SetHeader("Subject", email.Subject)
+	message.SetDateHeader("Date", email.SentAt)
+	message.SetBody("text/plain", email.PlainBody)
+	message.AddAlternative("text/html", email.HTMLBody)
+	return message
+}
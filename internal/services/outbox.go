@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/fdemchenko/arcus/internal/models"
+	"github.com/fdemchenko/arcus/internal/services/mail"
+)
+
+const (
+	outboxRetryBaseDelay = time.Second
+	outboxRetryMaxDelay  = 10 * time.Minute
+	outboxPublishTimeout = 10 * time.Second
+)
+
+type OutboxRepository interface {
+	Insert(ctx context.Context, message models.OutboxMessage) error
+	FetchPending(ctx context.Context, batchSize int, maxAttempts int) ([]models.OutboxMessage, error)
+	MarkDispatched(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, dispatchErr string, nextAttemptAt time.Time) error
+	PurgeFinished(ctx context.Context, maxAttempts int, before time.Time) (int, error)
+}
+
+type MailerProducer interface {
+	Publish(ctx context.Context, command mail.SendEmailCommand[any]) error
+}
+
+// OutboxRelay delivers messages stored in outbox table to the message broker.
+type OutboxRelay struct {
+	logger         *slog.Logger
+	unitOfWork     UnitOfWork[OutboxRepository]
+	mailerProducer MailerProducer
+	batchSize      int
+	maxAttempts    int
+}
+
+func NewOutboxRelay(
+	logger *slog.Logger,
+	unitOfWork UnitOfWork[OutboxRepository],
+	mailerProducer MailerProducer,
+	batchSize int,
+	maxAttempts int,
+) *OutboxRelay {
+	return &OutboxRelay{
+		logger:         logger,
+		unitOfWork:     unitOfWork,
+		mailerProducer: mailerProducer,
+		batchSize:      batchSize,
+		maxAttempts:    maxAttempts,
+	}
+}
+
+// Run drains the outbox every interval until ctx is done.
+func (or *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	const op = "services.OutboxRelay.Run"
+	logger := or.logger.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// keep draining while batches are full
+			for {
+				dispatched, err := or.dispatchBatch(ctx)
+				if err != nil {
+					logger.Error("failed to dispatch outbox messages", slog.String("error", err.Error()))
+					break
+				}
+				if dispatched > 0 {
+					logger.Debug("outbox messages processed", slog.Int("amount", dispatched))
+				}
+				if dispatched < or.batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RunPurge deletes dispatched and exhausted messages older than retention,
+// checking every interval until ctx is done.
+func (or *OutboxRelay) RunPurge(ctx context.Context, interval time.Duration, retention time.Duration) {
+	const op = "services.OutboxRelay.RunPurge"
+	logger := or.logger.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var purged int
+			err := or.unitOfWork.Do(ctx, func(outbox OutboxRepository) error {
+				var err error
+				purged, err = outbox.PurgeFinished(ctx, or.maxAttempts, time.Now().Add(-retention))
+				return err
+			})
+			if err != nil {
+				logger.Error("failed to purge outbox messages", slog.String("error", err.Error()))
+				continue
+			}
+			if purged > 0 {
+				logger.Info("outbox messages purged", slog.Int("amount", purged))
+			}
+		}
+	}
+}
+
+// dispatchBatch publishes a batch of pending messages and records the outcome
+// while the messages are locked. It returns amount of fetched messages.
+func (or *OutboxRelay) dispatchBatch(ctx context.Context) (int, error) {
+	const op = "services.OutboxRelay.dispatchBatch"
+	logger := or.logger.With(slog.String("op", op))
+
+	var fetched int
+	err := or.unitOfWork.Do(ctx, func(outbox OutboxRepository) error {
+		messages, err := outbox.FetchPending(ctx, or.batchSize, or.maxAttempts)
+		if err != nil {
+			return err
+		}
+		fetched = len(messages)
+
+		for _, message := range messages {
+			if dispatchErr := or.dispatch(ctx, message); dispatchErr != nil {
+				nextAttemptAt := time.Now().Add(outboxRetryDelay(message.Attempts + 1))
+				err = outbox.MarkFailed(ctx, message.ID, dispatchErr.Error(), nextAttemptAt)
+				if message.Attempts+1 >= or.maxAttempts {
+					// message is not fetched anymore and needs manual intervention
+					logger.Error("outbox message exhausted dispatch attempts",
+						slog.Int64("id", message.ID),
+						slog.String("topic", message.Topic),
+						slog.Int("attempts", message.Attempts+1),
+						slog.String("error", dispatchErr.Error()))
+				}
+			} else {
+				err = outbox.MarkDispatched(ctx, message.ID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return fetched, err
+}
+
+func (or *OutboxRelay) dispatch(ctx context.Context, message models.OutboxMessage) error {
+	switch message.Topic {
+	case models.TopicSendEmail:
+		var command mail.SendEmailCommand[json.RawMessage]
+		if err := json.Unmarshal(message.Payload, &command); err != nil {
+			return fmt.Errorf("cannot decode send email command: %w", err)
+		}
+		// bounds waiting for the broker confirmation as well
+		ctx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+		defer cancel()
+		return or.mailerProducer.Publish(ctx, mail.SendEmailCommand[any]{
+			To:           command.To,
+			TemplateName: command.TemplateName,
+			Locale:       command.Locale,
+			TemplateData: command.TemplateData,
+		})
+	default:
+		return fmt.Errorf("unknown outbox topic %q", message.Topic)
+	}
+}
+
+// outboxRetryDelay implements exponential backoff for failed dispatch attempts.
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBaseDelay << min(attempts, 20)
+	return min(delay, outboxRetryMaxDelay)
+}
+
+func newEmailOutboxMessage(command mail.SendEmailCommand[any]) (*models.OutboxMessage, error) {
+	payload, err := json.Marshal(command)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode send email command: %w", err)
+	}
+	return &models.OutboxMessage{Topic: models.TopicSendEmail, Payload: payload}, nil
+}
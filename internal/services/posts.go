@@ -1,47 +1,194 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/fdemchenko/arcus/internal/diff"
 	"github.com/fdemchenko/arcus/internal/models"
 )
 
 type PostsRepository interface {
-	Insert(models.Post) (int, error)
-	GetAll() ([]models.Post, error)
-	GetByID(int) (*models.Post, error)
-	DeleteByID(int) (int, error)
-	UpdateByID(models.Post) error
+	Insert(ctx context.Context, post models.Post) (int, error)
+	GetAll(ctx context.Context, filter models.PostsFilter) ([]models.Post, models.Metadata, error)
+	GetAllAfter(ctx context.Context, filter models.PostsFilter, after *models.PostsCursor) ([]models.Post, error)
+	GetByID(ctx context.Context, id int) (*models.Post, error)
+	DeleteByID(ctx context.Context, id int, version int) (int, error)
+	UpdateByID(ctx context.Context, post models.Post) error
+	InsertRevision(ctx context.Context, postID int, version int) error
+	InsertRevisionsByTags(ctx context.Context, tags []string) error
+	GetDeletedByID(ctx context.Context, id int) (*models.Post, error)
+	GetDeletedByUser(ctx context.Context, userID int) ([]models.Post, error)
+	Restore(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error)
+	GetRevisions(ctx context.Context, postID int) ([]models.PostRevision, error)
+	GetRevision(ctx context.Context, postID int, version int) (*models.PostRevision, error)
 }
 
 type PostsService struct {
-	logger          *slog.Logger
-	postsRepository PostsRepository
+	logger           *slog.Logger
+	postsRepository  PostsRepository
+	unitOfWork       UnitOfWork[PostsRepositories]
+	cursorSigningKey []byte
 }
 
-func NewPostsService(logger *slog.Logger, postsRepository PostsRepository) *PostsService {
+func NewPostsService(
+	logger *slog.Logger,
+	postsRepository PostsRepository,
+	unitOfWork UnitOfWork[PostsRepositories],
+	cursorSigningKey string,
+) *PostsService {
 	return &PostsService{
-		logger:          logger,
-		postsRepository: postsRepository,
+		logger:           logger,
+		postsRepository:  postsRepository,
+		unitOfWork:       unitOfWork,
+		cursorSigningKey: []byte(cursorSigningKey),
 	}
 }
 
-func (ps *PostsService) Create(post models.Post) (int, error) {
-	return ps.postsRepository.Insert(post)
+func (ps *PostsService) Create(ctx context.Context, post models.Post) (int, error) {
+	return ps.postsRepository.Insert(ctx, post)
 }
 
-func (ps *PostsService) GetAll() ([]models.Post, error) {
-	return ps.postsRepository.GetAll()
+func (ps *PostsService) GetAll(ctx context.Context, filter models.PostsFilter) ([]models.Post, models.Metadata, error) {
+	return ps.postsRepository.GetAll(ctx, filter)
 }
 
-func (ps *PostsService) GetByID(id int) (*models.Post, error) {
-	return ps.postsRepository.GetByID(id)
+// GetAllByCursor returns page of posts following the one cursor points to,
+// and cursor for the next page. Empty cursor means first page is requested,
+// empty next cursor means there are no more posts.
+func (ps *PostsService) GetAllByCursor(ctx context.Context, filter models.PostsFilter, cursor string) ([]models.Post, string, error) {
+	var after *models.PostsCursor
+	if cursor != "" {
+		decodedCursor, err := decodeCursor(cursor, ps.cursorSigningKey)
+		if err != nil {
+			return nil, "", err
+		}
+		after = decodedCursor
+	}
+
+	// fetch one extra post to find out whether next page exists
+	filter.PageSize++
+	posts, err := ps.postsRepository.GetAllAfter(ctx, filter, after)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(posts) < filter.PageSize {
+		return posts, "", nil
+	}
+
+	posts = posts[:len(posts)-1]
+	last := posts[len(posts)-1]
+	nextCursor, err := encodeCursor(models.PostsCursor{CreatedAt: last.CreatedAt, ID: last.ID}, ps.cursorSigningKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, nextCursor, nil
+}
+
+func (ps *PostsService) GetByID(ctx context.Context, id int) (*models.Post, error) {
+	return ps.postsRepository.GetByID(ctx, id)
+}
+
+func (ps *PostsService) DeleteByID(ctx context.Context, id int, version int) (int, error) {
+	return ps.postsRepository.DeleteByID(ctx, id, version)
+}
+
+// UpdateByID updates post if its version matches, previous post state
+// is stored as a revision within the same transaction.
+func (ps *PostsService) UpdateByID(ctx context.Context, post models.Post) error {
+	return ps.unitOfWork.Do(ctx, func(repositories PostsRepositories) error {
+		if err := repositories.Posts.InsertRevision(ctx, post.ID, post.Version); err != nil {
+			return err
+		}
+		return repositories.Posts.UpdateByID(ctx, post)
+	})
+}
+
+func (ps *PostsService) GetDeletedByID(ctx context.Context, id int) (*models.Post, error) {
+	return ps.postsRepository.GetDeletedByID(ctx, id)
+}
+
+func (ps *PostsService) GetTrash(ctx context.Context, userID int) ([]models.Post, error) {
+	return ps.postsRepository.GetDeletedByUser(ctx, userID)
+}
+
+func (ps *PostsService) Restore(ctx context.Context, id int) error {
+	return ps.postsRepository.Restore(ctx, id)
+}
+
+// RunTrashPurge permanently deletes posts which stay in trash longer than retention,
+// checking every interval until ctx is done.
+func (ps *PostsService) RunTrashPurge(ctx context.Context, interval time.Duration, retention time.Duration) {
+	const op = "services.PostsService.RunTrashPurge"
+	logger := ps.logger.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := ps.postsRepository.PurgeDeleted(ctx, time.Now().Add(-retention))
+			if err != nil {
+				logger.Error("failed to purge deleted posts", slog.String("error", err.Error()))
+				continue
+			}
+			if purged > 0 {
+				logger.Info("deleted posts purged", slog.Int("amount", purged))
+			}
+		}
+	}
+}
+
+func (ps *PostsService) GetRevisions(ctx context.Context, postID int) ([]models.PostRevision, error) {
+	return ps.postsRepository.GetRevisions(ctx, postID)
+}
+
+// GetRevision returns post state at the given version, which may be the current one.
+func (ps *PostsService) GetRevision(ctx context.Context, post models.Post, version int) (*models.PostRevision, error) {
+	if version == post.Version {
+		revision := models.RevisionFromPost(post)
+		return &revision, nil
+	}
+	return ps.postsRepository.GetRevision(ctx, post.ID, version)
 }
 
-func (ps *PostsService) DeleteByID(id int) (int, error) {
-	return ps.postsRepository.DeleteByID(id)
+func (ps *PostsService) DiffRevisions(ctx context.Context, post models.Post, fromVersion, toVersion int) (string, error) {
+	from, err := ps.GetRevision(ctx, post, fromVersion)
+	if err != nil {
+		return "", err
+	}
+	to, err := ps.GetRevision(ctx, post, toVersion)
+	if err != nil {
+		return "", err
+	}
+
+	fromName := fmt.Sprintf("post/%d@%d", post.ID, fromVersion)
+	toName := fmt.Sprintf("post/%d@%d", post.ID, toVersion)
+	return diff.Unified(fromName, toName, from.Text(), to.Text(), diff.DefaultContextLines)
 }
 
-func (ps *PostsService) UpdateByID(post models.Post) error {
-	return ps.postsRepository.UpdateByID(post)
+// RestoreRevision makes post content equal to the given revision, creating a new post version.
+func (ps *PostsService) RestoreRevision(ctx context.Context, post models.Post, version int) (*models.Post, error) {
+	revision, err := ps.postsRepository.GetRevision(ctx, post.ID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Title = revision.Title
+	post.Content = revision.Content
+	post.Tags = revision.Tags
+
+	err = ps.UpdateByID(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	post.Version++
+	return &post, nil
 }
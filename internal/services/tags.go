@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/fdemchenko/arcus/internal/models"
+)
+
+type TagsRepository interface {
+	GetAll(ctx context.Context) ([]models.Tag, error)
+	Merge(ctx context.Context, sources []string, target string) (int, error)
+}
+
+type TagsService struct {
+	logger         *slog.Logger
+	tagsRepository TagsRepository
+	unitOfWork     UnitOfWork[PostsRepositories]
+}
+
+func NewTagsService(logger *slog.Logger, tagsRepository TagsRepository, unitOfWork UnitOfWork[PostsRepositories]) *TagsService {
+	return &TagsService{
+		logger:         logger,
+		tagsRepository: tagsRepository,
+		unitOfWork:     unitOfWork,
+	}
+}
+
+func (ts *TagsService) GetAll(ctx context.Context) ([]models.Tag, error) {
+	return ts.tagsRepository.GetAll(ctx)
+}
+
+func (ts *TagsService) Merge(ctx context.Context, sources []string, target string) (int, error) {
+	const op = "services.TagsService.Merge"
+	logger := ts.logger.With(slog.String("op", op))
+
+	sources = models.NormalizeTags(sources)
+	target = models.NormalizeTag(target)
+
+	var updatedPosts int
+	err := ts.unitOfWork.Do(ctx, func(repositories PostsRepositories) error {
+		// merge bumps versions of affected posts, so their previous state is kept as revisions
+		if err := repositories.Posts.InsertRevisionsByTags(ctx, sources); err != nil {
+			return err
+		}
+
+		var err error
+		updatedPosts, err = repositories.Tags.Merge(ctx, sources, target)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	logger.Info("tags merged", slog.Any("sources", sources), slog.String("target", target), slog.Int("updated_posts", updatedPosts))
+	return updatedPosts, nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import "context"
+
+// UnitOfWork runs fn with repositories bound to a single transaction.
+// Transaction is committed if fn succeeds, and rolled back if it returns error or panics.
+type UnitOfWork[R any] interface {
+	Do(ctx context.Context, fn func(repositories R) error) error
+}
+
+// UsersRepositories are repositories taking part in users related transactions.
+type UsersRepositories struct {
+	Users  UsersRepository
+	Tokens TokensRepository
+	Outbox OutboxRepository
+}
+
+// PostsRepositories are repositories taking part in posts related transactions.
+type PostsRepositories struct {
+	Posts PostsRepository
+	Tags  TagsRepository
+}
@@ -1,155 +1,352 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"time"
 
 	"github.com/fdemchenko/arcus/internal/models"
+	"github.com/fdemchenko/arcus/internal/repositories/postgres"
 	"github.com/fdemchenko/arcus/internal/services/mail"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrActivationTokenExpired = errors.New("activation token has expired")
+	ErrInvalidCredentials     = errors.New("invalid authentication credentials")
 )
 
 type UsersRepository interface {
-	Insert(user models.User) (int, error)
-	Activate(userID int) error
-	GetByID(userID int) (*models.User, error)
+	Activate(ctx context.Context, userID int) error
+	GetByID(ctx context.Context, userID int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Insert(ctx context.Context, user models.User) (int, error)
+	UpdatePassword(ctx context.Context, userID int, passwordHash []byte) error
 }
 
 type TokensRepository interface {
-	Insert(models.Token) error
-	GetByTokenHash(hash []byte, scope string) (*models.Token, error)
-	DeleteAllForUser(userID int, scope string) error
-}
-
-type MailerProducer interface {
-	Publish(command mail.SendEmailCommand[any]) error
+	Insert(ctx context.Context, token models.Token) error
+	GetByTokenHash(ctx context.Context, hash []byte, scope string) (*models.Token, error)
+	DeleteAllForUser(ctx context.Context, userID int, scope string) error
 }
 
 type UsersService struct {
-	usersRepository    UsersRepository
-	tokensRepository   TokensRepository
-	mailerProducer     MailerProducer
-	logger             *slog.Logger
-	activationTokenTTL time.Duration
+	usersRepository        UsersRepository
+	tokensRepository       TokensRepository
+	unitOfWork             UnitOfWork[UsersRepositories]
+	logger                 *slog.Logger
+	activationTokenTTL     time.Duration
+	authenticationTokenTTL time.Duration
+	passwordResetTokenTTL  time.Duration
+	activationURL          string
 }
 
 func NewUserService(
 	usersRepository UsersRepository,
 	logger *slog.Logger,
 	tokensRepository TokensRepository,
-	mailerProducer MailerProducer,
+	unitOfWork UnitOfWork[UsersRepositories],
 	activationTokenTTL time.Duration,
+	authenticationTokenTTL time.Duration,
+	passwordResetTokenTTL time.Duration,
+	activationURL string,
 ) *UsersService {
 	return &UsersService{
-		usersRepository:    usersRepository,
-		logger:             logger,
-		tokensRepository:   tokensRepository,
-		mailerProducer:     mailerProducer,
-		activationTokenTTL: activationTokenTTL,
+		usersRepository:        usersRepository,
+		logger:                 logger,
+		tokensRepository:       tokensRepository,
+		unitOfWork:             unitOfWork,
+		activationTokenTTL:     activationTokenTTL,
+		authenticationTokenTTL: authenticationTokenTTL,
+		passwordResetTokenTTL:  passwordResetTokenTTL,
+		activationURL:          activationURL,
 	}
 }
 
-func (us *UsersService) Register(user models.User) (int, error) {
+func (us *UsersService) Register(ctx context.Context, user models.User) (int, error) {
 	const op = "services.UserService.Register"
 	logger := us.logger.With("op", op)
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password.Plain), bcrypt.DefaultCost)
 	if err != nil {
 		logger.Error("failed to hash password with bcrypt", slog.String("error", err.Error()))
-		return 0, nil
+		return 0, err
 	}
-
 	user.Password.Hash = hash
-	newUserID, err := us.usersRepository.Insert(user)
+
+	// user ID is assigned to the token once user is inserted
+	activationToken, err := models.GenerateToken(models.ScopeActivation, us.activationTokenTTL, 0)
 	if err != nil {
-		logger.Error("failed to create new user in DB", slog.String("error", err.Error()))
+		logger.Error("failed to create activation token", slog.String("error", err.Error()))
 		return 0, err
 	}
-	user.ID = newUserID
-	err = us.SendActivationToken(user)
+
+	message, err := newEmailOutboxMessage(mail.SendEmailCommand[any]{
+		To:           user.Email,
+		Locale:       user.Locale,
+		TemplateName: mail.UserWelcomeTemplate,
+		TemplateData: mail.UserWelcomeData{
+			Token:          activationToken.PlainText,
+			Name:           user.Name,
+			ActivationLink: us.activationLink(activationToken.PlainText),
+		},
+	})
 	if err != nil {
-		logger.Error("failed to send activation token", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	var newUserID int
+	err = us.unitOfWork.Do(ctx, func(repositories UsersRepositories) error {
+		newUserID, err = repositories.Users.Insert(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		activationToken.UserID = newUserID
+		if err := repositories.Tokens.Insert(ctx, *activationToken); err != nil {
+			return err
+		}
+		return repositories.Outbox.Insert(ctx, *message)
+	})
+	if err != nil {
+		logger.Error("failed to create new user in DB", slog.String("error", err.Error()))
+		return 0, err
 	}
 	return newUserID, nil
 }
 
-func (us *UsersService) SendActivationToken(user models.User) error {
+func (us *UsersService) SendActivationToken(ctx context.Context, user models.User) error {
 	const op = "services.UserService.SendActivationToken"
 	logger := us.logger.With(slog.String("op", op))
 
-	err := us.tokensRepository.DeleteAllForUser(user.ID, models.ScopeActivation)
-	if err != nil {
-		return fmt.Errorf("failed to delete activation tokens from DB: %w", err)
-	}
-
 	activationToken, err := models.GenerateToken(models.ScopeActivation, us.activationTokenTTL, user.ID)
 	if err != nil {
 		logger.Error("failed to create activation token", slog.String("error", err.Error()))
 		return err
 	}
 
-	err = us.tokensRepository.Insert(*activationToken)
+	message, err := newEmailOutboxMessage(mail.SendEmailCommand[any]{
+		To:           user.Email,
+		Locale:       user.Locale,
+		TemplateName: mail.UserWelcomeTemplate,
+		TemplateData: mail.UserWelcomeData{
+			Token:          activationToken.PlainText,
+			Name:           user.Name,
+			ActivationLink: us.activationLink(activationToken.PlainText),
+		},
+	})
 	if err != nil {
-		logger.Error("failed to insert token to DB", slog.String("error", err.Error()))
 		return err
 	}
 
-	command := mail.SendEmailCommand[any]{
-		To:           user.Email,
-		TemplateName: "user_welcome.tmpl",
-		TemplateData: mail.UserWelcomeData{Token: activationToken.PlainText, Name: user.Name},
-	}
-	err = us.mailerProducer.Publish(command)
+	err = us.replaceTokenWithOutbox(ctx, *activationToken, *message)
 	if err != nil {
-		logger.Error("failed to send email command", slog.String("error", err.Error()))
+		logger.Error("failed to store activation token", slog.String("error", err.Error()))
 	}
 	return err
 }
 
-func (us *UsersService) GetByID(userID int) (*models.User, error) {
-	return us.usersRepository.GetByID(userID)
+func (us *UsersService) activationLink(tokenPlaintext string) string {
+	return us.activationURL + "?" + url.Values{"token": {tokenPlaintext}}.Encode()
 }
 
-func (us *UsersService) Activate(tokenPlaintext string) error {
+func (us *UsersService) GetByID(ctx context.Context, userID int) (*models.User, error) {
+	return us.usersRepository.GetByID(ctx, userID)
+}
+
+func (us *UsersService) Activate(ctx context.Context, tokenPlaintext string) error {
 	const op = "services.UserService.Activate"
 	logger := us.logger.With(slog.String("op", op))
 
-	// decode to bytes
-	tokenBytes := make([]byte, len(tokenPlaintext)/2)
-	_, err := hex.Decode(tokenBytes, []byte(tokenPlaintext))
+	tokenHash, err := hashTokenPlaintext(tokenPlaintext)
 	if err != nil {
 		logger.Error("failed to decode hex-encoded token", slog.String("error", err.Error()))
 		return err
 	}
 
-	// get the token hash
-	tokenSha256 := sha256.Sum256(tokenBytes)
+	token, err := us.tokensRepository.GetByTokenHash(ctx, tokenHash, models.ScopeActivation)
+	if err != nil {
+		logger.Error("failed to retrieve token from DB", slog.String("error", err.Error()))
+		return err
+	}
+
+	err = us.unitOfWork.Do(ctx, func(repositories UsersRepositories) error {
+		if err := repositories.Users.Activate(ctx, token.UserID); err != nil {
+			return fmt.Errorf("failed to update user record: %w", err)
+		}
+		if err := repositories.Tokens.DeleteAllForUser(ctx, token.UserID, models.ScopeActivation); err != nil {
+			return fmt.Errorf("failed to delete activation tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to activate user", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (us *UsersService) Authenticate(ctx context.Context, email, password string) (*models.Token, error) {
+	const op = "services.UserService.Authenticate"
+	logger := us.logger.With(slog.String("op", op))
+
+	user, err := us.usersRepository.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUserDoesNotExists) {
+			return nil, ErrInvalidCredentials
+		}
+		logger.Error("failed to retrieve user from DB", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword(user.Password.Hash, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return nil, ErrInvalidCredentials
+		}
+		logger.Error("failed to compare password hash", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	authToken, err := models.GenerateToken(models.ScopeAuthentication, us.authenticationTokenTTL, user.ID)
+	if err != nil {
+		logger.Error("failed to create authentication token", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	err = us.tokensRepository.Insert(ctx, *authToken)
+	if err != nil {
+		logger.Error("failed to insert token to DB", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return authToken, nil
+}
+
+func (us *UsersService) GetForToken(ctx context.Context, tokenPlaintext string, scope string) (*models.User, error) {
+	const op = "services.UserService.GetForToken"
+	logger := us.logger.With(slog.String("op", op))
 
-	token, err := us.tokensRepository.GetByTokenHash(tokenSha256[:], models.ScopeActivation)
+	tokenHash, err := hashTokenPlaintext(tokenPlaintext)
+	if err != nil {
+		return nil, postgres.ErrTokenNotFound
+	}
+
+	token, err := us.tokensRepository.GetByTokenHash(ctx, tokenHash, scope)
+	if err != nil {
+		if !errors.Is(err, postgres.ErrTokenNotFound) {
+			logger.Error("failed to retrieve token from DB", slog.String("error", err.Error()))
+		}
+		return nil, err
+	}
+
+	user, err := us.usersRepository.GetByID(ctx, token.UserID)
+	if err != nil {
+		logger.Error("failed to retrieve token owner from DB", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return user, nil
+}
+
+func (us *UsersService) SendPasswordResetToken(ctx context.Context, email string) error {
+	const op = "services.UserService.SendPasswordResetToken"
+	logger := us.logger.With(slog.String("op", op))
+
+	user, err := us.usersRepository.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	resetToken, err := models.GenerateToken(models.ScopePasswordReset, us.passwordResetTokenTTL, user.ID)
+	if err != nil {
+		logger.Error("failed to create password reset token", slog.String("error", err.Error()))
+		return err
+	}
+
+	message, err := newEmailOutboxMessage(mail.SendEmailCommand[any]{
+		To:           user.Email,
+		Locale:       user.Locale,
+		TemplateName: mail.PasswordResetTemplate,
+		TemplateData: mail.PasswordResetData{Token: resetToken.PlainText, Name: user.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = us.replaceTokenWithOutbox(ctx, *resetToken, *message)
+	if err != nil {
+		logger.Error("failed to store password reset token", slog.String("error", err.Error()))
+	}
+	return err
+}
+
+func (us *UsersService) ResetPassword(ctx context.Context, tokenPlaintext string, newPassword string) error {
+	const op = "services.UserService.ResetPassword"
+	logger := us.logger.With(slog.String("op", op))
+
+	tokenHash, err := hashTokenPlaintext(tokenPlaintext)
+	if err != nil {
+		logger.Error("failed to decode hex-encoded token", slog.String("error", err.Error()))
+		return postgres.ErrTokenNotFound
+	}
+
+	token, err := us.tokensRepository.GetByTokenHash(ctx, tokenHash, models.ScopePasswordReset)
 	if err != nil {
 		logger.Error("failed to retrieve token from DB", slog.String("error", err.Error()))
 		return err
 	}
 
-	defer func() {
-		err := us.tokensRepository.DeleteAllForUser(token.UserID, models.ScopeActivation)
-		if err != nil {
-			logger.Error("failed to delete activation token", slog.String("error", err.Error()))
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Error("failed to hash password with bcrypt", slog.String("error", err.Error()))
+		return err
+	}
+
+	err = us.unitOfWork.Do(ctx, func(repositories UsersRepositories) error {
+		if err := repositories.Users.UpdatePassword(ctx, token.UserID, hash); err != nil {
+			return fmt.Errorf("failed to update user password: %w", err)
 		}
-	}()
 
-	err = us.usersRepository.Activate(token.UserID)
+		// reset token is single-use, and existing sessions must not survive the password change
+		for _, scope := range []string{models.ScopePasswordReset, models.ScopeAuthentication} {
+			if err := repositories.Tokens.DeleteAllForUser(ctx, token.UserID, scope); err != nil {
+				return fmt.Errorf("failed to delete %s tokens: %w", scope, err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		logger.Error("failed to update user record", slog.String("error", err.Error()))
+		logger.Error("failed to reset password", slog.String("error", err.Error()))
 		return err
 	}
 	return nil
 }
+
+// replaceTokenWithOutbox deletes user tokens of the same scope, inserts the new one
+// and stores outbox message delivering it in a single transaction.
+func (us *UsersService) replaceTokenWithOutbox(ctx context.Context, token models.Token, message models.OutboxMessage) error {
+	return us.unitOfWork.Do(ctx, func(repositories UsersRepositories) error {
+		if err := repositories.Tokens.DeleteAllForUser(ctx, token.UserID, token.Scope); err != nil {
+			return err
+		}
+		if err := repositories.Tokens.Insert(ctx, token); err != nil {
+			return err
+		}
+		return repositories.Outbox.Insert(ctx, message)
+	})
+}
+
+// hashTokenPlaintext decodes hex-encoded token and returns its SHA-256 hash,
+// the same way it is stored in DB by models.GenerateToken.
+func hashTokenPlaintext(tokenPlaintext string) ([]byte, error) {
+	tokenBytes, err := hex.DecodeString(tokenPlaintext)
+	if err != nil {
+		return nil, err
+	}
+	tokenSha256 := sha256.Sum256(tokenBytes)
+	return tokenSha256[:], nil
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS posts_user_id_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS user_id;
\ No newline at end of file
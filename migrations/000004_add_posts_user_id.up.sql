@@ -0,0 +1,3 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS user_id INT NULL REFERENCES users ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS posts_user_id_idx ON posts (user_id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS posts_tags_idx;
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS posts_tags_idx ON posts USING GIN (tags);
\ No newline at end of file
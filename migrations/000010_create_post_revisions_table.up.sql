@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts ON DELETE CASCADE,
+    version INT NOT NULL,
+    title VARCHAR(70) NOT NULL,
+    content TEXT NULL,
+    tags text[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (post_id, version)
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- tags normalization is irreversible, original spelling is not kept
+SELECT 1;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- tags are normalized by the application since the tag registry was introduced, bring older rows in line
+UPDATE posts SET tags = normalized.tags
+FROM (
+    SELECT p.id, ARRAY(
+        SELECT t.tag FROM (
+            SELECT lower(btrim(u.tag)) AS tag, min(u.position) AS position
+            FROM unnest(p.tags) WITH ORDINALITY AS u(tag, position)
+            WHERE btrim(u.tag) <> ''
+            GROUP BY lower(btrim(u.tag))
+        ) t
+        ORDER BY t.position
+    ) AS tags
+    FROM posts p
+) normalized
+WHERE posts.id = normalized.id AND posts.tags <> normalized.tags;